@@ -0,0 +1,165 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cli
+
+import (
+	"fmt"
+	"goss/internal/config"
+	"goss/internal/control"
+	"goss/internal/dispatcher"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Manage the persistent connection agent (control master).",
+	Long: `Run a background process that keeps authenticated SSH connections open on a local unix socket.
+While the agent is running, exec and apply send their tasks through it instead of
+connecting to every host again, similar to OpenSSH ControlMaster.`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent in the background.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := loadAgentConfig(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		foreground, _ := cmd.Flags().GetBool("foreground")
+		if foreground {
+			l, err := control.Listen(cfg.Connection.ControlPath)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := dispatcher.ServeAgent(l, cfg); err != nil {
+				fmt.Printf("goss agent exited with error %s\n", err)
+			}
+			return
+		}
+		if err := startAgentProcess(cfg); err != nil {
+			fmt.Printf("Failed to start goss agent %s\n", err)
+			return
+		}
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running agent and close its connections.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := loadAgentConfig(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		resp, err := control.Call(cfg.Connection.ControlPath, &control.Request{Op: control.OpShutdown})
+		if err != nil {
+			fmt.Printf("goss agent is not running on %s\n", control.SocketPath(cfg.Connection.ControlPath))
+			return
+		}
+		fmt.Printf("goss agent (pid %d) stopped\n", resp.Pid)
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the agent is running and how many connections it holds.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := loadAgentConfig(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		socket := control.SocketPath(cfg.Connection.ControlPath)
+		resp, err := control.Call(cfg.Connection.ControlPath, &control.Request{Op: control.OpPing})
+		if err != nil {
+			fmt.Printf("goss agent is not running on %s\n", socket)
+			return
+		}
+		fmt.Printf("goss agent is running\n  pid: %d\n  socket: %s\n  connections: %d\n  uptime: %s\n",
+			resp.Pid, socket, resp.Connections, time.Duration(resp.Uptime)*time.Second)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentStartCmd, agentStopCmd, agentStatusCmd)
+	agentCmd.PersistentFlags().String("socket", "", "Control socket path (default: connection.control_path)")
+	agentStartCmd.Flags().Int("ttl", 0, "Seconds an idle connection is kept open (default: connection.control_persist)")
+	agentStartCmd.Flags().Bool("foreground", false, "Run the agent in the foreground")
+}
+
+// loadAgentConfig 加载全局配置并应用命令行覆盖
+func loadAgentConfig(cmd *cobra.Command) (*config.GossConfig, error) {
+	cfg, err := config.LoadConfig(ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if socket, _ := cmd.Flags().GetString("socket"); socket != "" {
+		cfg.Connection.ControlPath = socket
+	}
+	if cfg.Connection.ControlPath == "" {
+		return nil, fmt.Errorf("control socket path is empty, set connection.control_path or --socket")
+	}
+	if cmd.Flags().Lookup("ttl") != nil {
+		if ttl, _ := cmd.Flags().GetInt("ttl"); ttl > 0 {
+			cfg.Connection.ControlPersist = ttl
+		}
+	}
+	return cfg, nil
+}
+
+// startAgentProcess 以脱离终端的子进程方式启动agent，日志写入套接字所在目录
+func startAgentProcess(cfg *config.GossConfig) error {
+	socket := control.SocketPath(cfg.Connection.ControlPath)
+	if control.Ping(socket) == nil {
+		return fmt.Errorf("goss agent is already running on %s", socket)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return err
+	}
+	logPath := filepath.Join(filepath.Dir(socket), "agent.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	child := exec.Command(self, "agent", "start", "--foreground",
+		"--config", ConfigPath,
+		"--socket", socket,
+		"--ttl", fmt.Sprint(cfg.Connection.ControlPersist))
+	// 后台进程没有终端，无法提示输入vault口令
+	if VaultPasswordFile != "" {
+		child.Args = append(child.Args, "--vault-password-file", VaultPasswordFile)
+	}
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := child.Start(); err != nil {
+		return err
+	}
+	// 等待套接字就绪
+	for i := 0; i < 50; i++ {
+		if control.Ping(socket) == nil {
+			fmt.Printf("goss agent started (pid %d)\n  socket: %s\n  log: %s\n", child.Process.Pid, socket, logPath)
+			return child.Process.Release()
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("goss agent did not become ready, see %s", logPath)
+}
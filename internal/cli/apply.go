@@ -28,6 +28,19 @@ var applyCmd = &cobra.Command{
 			fmt.Println(err)
 			return
 		}
+		// 按 --limit 筛选主机
+		hosts, listOnly, err := selectHosts(cmd, hosts)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if listOnly {
+			return
+		}
+		if err := prepareHosts(cfg, hosts); err != nil {
+			fmt.Println(err)
+			return
+		}
 		// 加载配置
 		tasks, err := config.LoadTasks(taskPath)
 		if err != nil {
@@ -41,6 +54,7 @@ var applyCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(applyCmd)
+	addLimitFlags(applyCmd)
 	applyCmd.Flags().StringP("file", "f", "./tasks.yml", "Task configuration file path")
 	applyCmd.MarkFlagRequired("file")
 }
@@ -8,6 +8,7 @@ import (
 
 	"goss/internal/config"
 	"goss/internal/dispatcher"
+	"goss/pkg/easyssh"
 
 	"github.com/spf13/cobra"
 )
@@ -31,18 +32,36 @@ and supporting direct specification of task parameters.`,
 			fmt.Println(err)
 			return
 		}
+		// 按 --limit 筛选主机
+		hosts, listOnly, err := selectHosts(cmd, hosts)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if listOnly {
+			return
+		}
+		if err := prepareHosts(cfg, hosts); err != nil {
+			fmt.Println(err)
+			return
+		}
 		dispatcher.Run(hosts, tasks, cfg, Save)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(execCmd)
+	addLimitFlags(execCmd)
 	// Task execution parameters
-	execCmd.Flags().String("type", "", "Command execution types: script, cmd, download, upload")
+	execCmd.Flags().String("type", "", "Command execution types: script, cmd, download, upload, facts")
 	execCmd.Flags().String("cmd", "", "Command string (required for 'cmd' type)")
 	execCmd.Flags().String("local", "", "Local file/directory path (required for 'upload'/'download'/'script' types)")
 	execCmd.Flags().String("remote", "", "Remote file/directory path (required for 'upload'/'download'/'script' types)")
 	execCmd.Flags().Bool("sudo", false, "Require sudo privileges for execution")
+	execCmd.Flags().String("become-method", "", "Privilege escalation method used with --sudo: su, sudo, doas, none (default from inventory, otherwise su)")
+	execCmd.Flags().String("become-user", "", "User to become with --sudo (default from inventory, otherwise root)")
+	execCmd.Flags().Bool("forward-agent", false, "Forward the local ssh-agent into the remote session")
+	execCmd.Flags().Bool("template", false, "Render the cmd with inventory variables, e.g. {{ .Vars.app_dir }}")
 	// 必须条件配置
 
 }
@@ -70,14 +89,37 @@ func buildTaskConfig(cmd *cobra.Command) ([]*config.Task, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task sudo: %s", err.Error())
 	}
+	taskBecomeMethod, err := cmd.Flags().GetString("become-method")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task become-method: %s", err.Error())
+	}
+	taskBecomeUser, err := cmd.Flags().GetString("become-user")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task become-user: %s", err.Error())
+	}
+	if taskBecomeMethod != "" && !easyssh.ValidBecomeMethod(taskBecomeMethod) {
+		return nil, fmt.Errorf("invalid --become-method %q, must be one of su, sudo, doas, none", taskBecomeMethod)
+	}
+	taskForwardAgent, err := cmd.Flags().GetBool("forward-agent")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task forward-agent: %s", err.Error())
+	}
+	taskTemplate, err := cmd.Flags().GetBool("template")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task template: %s", err.Error())
+	}
 	return []*config.Task{
 		{
-			Type:        config.TaskType(taskType),
-			Description: "Single execution",
-			Cmd:         taskCmd,
-			RequireSudo: taskSudo,
-			Local:       taskLocal,
-			Remote:      taskRemote,
+			Type:         config.TaskType(taskType),
+			Description:  "Single execution",
+			Cmd:          taskCmd,
+			RequireSudo:  taskSudo,
+			BecomeMethod: taskBecomeMethod,
+			BecomeUser:   taskBecomeUser,
+			Local:        taskLocal,
+			Remote:       taskRemote,
+			ForwardAgent: taskForwardAgent,
+			Template:     taskTemplate,
 		},
 	}, nil
 }
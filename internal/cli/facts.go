@@ -0,0 +1,53 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cli
+
+import (
+	"fmt"
+	"goss/internal/dispatcher"
+	"goss/internal/printer"
+
+	"github.com/spf13/cobra"
+)
+
+// factsCmd represents the facts command
+var factsCmd = &cobra.Command{
+	Use:   "facts",
+	Short: "Gather OS, kernel, CPU, memory, disk, network, init system and package manager facts.",
+	Long: `Connect to every selected host and collect structured facts. Results are cached per host
+for facts.cache_ttl seconds and are also available to task templates, e.g. {{ .Facts.OS.Family }}.
+Use --save json or --save excel to export them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		refresh, err := cmd.Flags().GetBool("refresh")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		hosts, cfg, err := basicConfigurationParserconfigParser(HostPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		hosts, listOnly, err := selectHosts(cmd, hosts)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if listOnly {
+			return
+		}
+		if err := prepareHosts(cfg, hosts); err != nil {
+			fmt.Println(err)
+			return
+		}
+		results := dispatcher.GatherFacts(hosts, cfg, refresh)
+		printer.PrintFacts(results, printer.Format(Save))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(factsCmd)
+	addLimitFlags(factsCmd)
+	factsCmd.Flags().Bool("refresh", false, "Ignore cached facts and gather them again")
+}
@@ -0,0 +1,278 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cli
+
+import (
+	"fmt"
+	"goss/internal/config"
+	"goss/internal/pool"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// hostkeysCmd represents the hostkeys command
+var hostkeysCmd = &cobra.Command{
+	Use:   "hostkeys",
+	Short: "Scan, pin and audit SSH host keys of the inventory.",
+	Long: `Collect server host keys of every host in the inventory without authenticating,
+so that known_hosts can be populated before switching to strict security mode.`,
+}
+
+var hostkeysScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Collect host keys and write unknown ones to known_hosts.",
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, results, err := scanInventory(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		replace, _ := cmd.Flags().GetBool("replace")
+		var (
+			lines        []string
+			replaceHosts []string
+			added        int
+			changed      int
+		)
+		for _, r := range results {
+			if r.err != nil {
+				continue
+			}
+			hostReplaced := false
+			for _, k := range r.keys {
+				switch k.status {
+				case easyssh.KeyUnknown:
+					lines = append(lines, easyssh.KnownHostsLine(r.addr, k.key, opts.hash))
+					added++
+				case easyssh.KeyChanged:
+					changed++
+					if !replace {
+						continue
+					}
+					if !hostReplaced {
+						replaceHosts = append(replaceHosts, r.addr)
+						hostReplaced = true
+					}
+					lines = append(lines, easyssh.KnownHostsLine(r.addr, k.key, opts.hash))
+				}
+			}
+			// 替换主机时同时保留该主机未变化的其他类型密钥
+			if hostReplaced {
+				for _, k := range r.keys {
+					if k.status == easyssh.KeyKnown {
+						lines = append(lines, easyssh.KnownHostsLine(r.addr, k.key, opts.hash))
+					}
+				}
+			}
+		}
+		printKeyTable(results)
+		if len(lines) > 0 {
+			if err := easyssh.AppendKnownHosts(opts.path, lines, replaceHosts); err != nil {
+				fmt.Printf("Failed to update known_hosts %s\n", err)
+				return
+			}
+		}
+		fmt.Printf("known_hosts: %s, %d key(s) added", opts.path, added)
+		if replace {
+			fmt.Printf(", %d changed key(s) replaced\n", changed)
+		} else if changed > 0 {
+			fmt.Printf(", %d changed key(s) NOT written (use --replace after verifying them)\n", changed)
+		} else {
+			fmt.Println()
+		}
+	},
+}
+
+var hostkeysVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare the keys presented by every host with known_hosts.",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, results, err := scanInventory(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		printKeyTable(results)
+		counts := make(map[easyssh.KeyStatus]int)
+		failed := 0
+		for _, r := range results {
+			if r.err != nil {
+				failed++
+				continue
+			}
+			for _, k := range r.keys {
+				counts[k.status]++
+			}
+		}
+		fmt.Printf("known: %d, unknown: %d, changed: %d, revoked: %d, unreachable hosts: %d\n",
+			counts[easyssh.KeyKnown], counts[easyssh.KeyUnknown], counts[easyssh.KeyChanged], counts[easyssh.KeyRevoked], failed)
+	},
+}
+
+var hostkeysDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the known_hosts changes needed to match the keys presented by the hosts.",
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, results, err := scanInventory(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("--- %s\n+++ scanned\n", opts.path)
+		for _, r := range results {
+			if r.err != nil {
+				fmt.Printf("# %s: %s\n", r.addr, r.err)
+				continue
+			}
+			for _, k := range r.keys {
+				switch k.status {
+				case easyssh.KeyChanged:
+					for _, old := range k.known {
+						fmt.Printf("- %s %s %s (%s:%d)\n", r.addr, old.Key.Type(), ssh.FingerprintSHA256(old.Key), old.Filename, old.Line)
+					}
+					fmt.Printf("+ %s\n", easyssh.KnownHostsLine(r.addr, k.key, opts.hash))
+				case easyssh.KeyUnknown:
+					fmt.Printf("+ %s\n", easyssh.KnownHostsLine(r.addr, k.key, opts.hash))
+				case easyssh.KeyRevoked:
+					fmt.Printf("! %s %s %s is revoked\n", r.addr, k.key.Type(), ssh.FingerprintSHA256(k.key))
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hostkeysCmd)
+	hostkeysCmd.AddCommand(hostkeysScanCmd, hostkeysVerifyCmd, hostkeysDiffCmd)
+	hostkeysCmd.PersistentFlags().String("known-hosts", "", "known_hosts file path (default: connection.known_hosts_file or ~/.ssh/known_hosts)")
+	hostkeysCmd.PersistentFlags().Bool("hash", false, "Write host names in hashed (|1|) form")
+	hostkeysCmd.PersistentFlags().StringSlice("types", []string{"ed25519", "ecdsa", "rsa"}, "Host key types to collect: ed25519, ecdsa, rsa")
+	hostkeysCmd.PersistentFlags().Int("workers", 20, "Number of hosts scanned concurrently")
+	hostkeysScanCmd.Flags().Bool("replace", false, "Replace stored keys that changed")
+}
+
+type scanOptions struct {
+	path string
+	hash bool
+}
+
+type scannedKey struct {
+	key    ssh.PublicKey
+	status easyssh.KeyStatus
+	known  []knownhosts.KnownKey
+}
+
+type scanResult struct {
+	addr string
+	keys []scannedKey
+	err  error
+}
+
+// 命令行密钥类型与ssh算法名称的对应关系
+var scanKeyTypes = map[string]string{
+	"ed25519": ssh.KeyAlgoED25519,
+	"ecdsa":   ssh.KeyAlgoECDSA256,
+	"rsa":     ssh.KeyAlgoRSASHA512,
+}
+
+// scanInventory 并发收集主机清单中所有主机的密钥并与known_hosts比对，结果顺序与清单一致
+func scanInventory(cmd *cobra.Command) (*scanOptions, []*scanResult, error) {
+	hosts, cfg, err := basicConfigurationParserconfigParser(HostPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	path, _ := cmd.Flags().GetString("known-hosts")
+	if path == "" {
+		path = cfg.Connection.KnownHostsFile
+	}
+	path, err = easyssh.KnownHostsFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, _ := cmd.Flags().GetBool("hash")
+	types, _ := cmd.Flags().GetStringSlice("types")
+	var algorithms []string
+	for _, t := range types {
+		algo, ok := scanKeyTypes[strings.ToLower(strings.TrimSpace(t))]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported host key type %q, expected ed25519, ecdsa or rsa", t)
+		}
+		algorithms = append(algorithms, algo)
+	}
+	workers, _ := cmd.Flags().GetInt("workers")
+	if workers <= 0 {
+		workers = 1
+	}
+	// 位于跳板机之后的主机需要经过已认证的跳板连接扫描
+	connPool := pool.New(cfg)
+	defer connPool.Close()
+	timeout := time.Duration(cfg.Connection.ConnectTimeout) * time.Second
+
+	results := make([]*scanResult, len(hosts))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, host *config.Host) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = scanHost(connPool, host, path, algorithms, timeout)
+		}(i, host)
+	}
+	wg.Wait()
+	return &scanOptions{path: path, hash: hash}, results, nil
+}
+
+func scanHost(connPool *pool.Pool, host *config.Host, path string, algorithms []string, timeout time.Duration) *scanResult {
+	r := &scanResult{addr: net.JoinHostPort(host.IP, host.Port)}
+	via, err := connPool.JumpFor(host)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	keys, err := easyssh.ScanHostKeys(r.addr, via, algorithms, timeout)
+	if err != nil {
+		slog.Warn("Failed to collect host keys", "host", r.addr, "error", err)
+		r.err = err
+		return r
+	}
+	for _, key := range keys {
+		status, known, err := easyssh.CheckKnownHost(path, r.addr, key)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		r.keys = append(r.keys, scannedKey{key: key, status: status, known: known})
+	}
+	return r
+}
+
+func printKeyTable(results []*scanResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Host", "Type", "Fingerprint", "Status"})
+	for _, r := range results {
+		if r.err != nil {
+			t.AppendRow(table.Row{r.addr, "-", "-", "error: " + r.err.Error()})
+			continue
+		}
+		for _, k := range r.keys {
+			t.AppendRow(table.Row{r.addr, k.key.Type(), ssh.FingerprintSHA256(k.key), k.status})
+		}
+	}
+	t.Render()
+}
@@ -51,19 +51,88 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 }
 
-const hostsTemplate = `# 格式：IP,用户名,登录密码,特权密码
+const hostsTemplate = `# 格式：地址,用户名,登录密码,特权密码
+# 地址可以是IP、主机名或IPv6地址，非默认端口写作 地址:端口，IPv6写作 [地址]:端口
 # 示例：
 # 192.168.1.101,admin,P@ssw0rd123,SudoP@ss!
-# 10.0.5.17,deploy,Deploy123,Root!789
+# 10.0.5.17:2222,deploy,Deploy123,Root!789
+# web01.example.com,deploy,Deploy123,
+# 2001:db8::10,deploy,Deploy123,
+# [2001:db8::11]:2222,deploy,Deploy123,
 # 172.16.0.33,ubuntu,UbuntuPass,  # 无特权账户留空
+# 地址支持范围与网段，展开后的主机共用同一行的参数，展开后出现重复主机时报错：
+# 10.20.3.[1:64],deploy,Deploy123,       # 数字范围
+# db[01:12].prod,deploy,Deploy123,       # 补零范围 db01..db12
+# web[a:c],deploy,Deploy123,             # 字母范围
+# 10.20.4.[0:200:10],deploy,Deploy123,   # 步长
+# 10.20.5.0/28,deploy,Deploy123,         # CIDR网段，跳过网络地址与广播地址
+# 密码可以使用 goss vault encrypt --value '密码' 生成的加密值代替，也可以用 goss vault encrypt hosts.ini 加密整个文件，
+# 执行时通过 --vault-password-file 指定口令文件或在终端中输入口令：
+# 10.0.5.16,admin,$GOSS_VAULT;1.0;AES256-GCM;xxxx,$GOSS_VAULT;1.0;AES256-GCM;yyyy
+# 密码列也可以引用外部来源：env:环境变量名、file:文件路径、cmd:输出密码的命令，只在连接选中的主机前解析；
+# 以这些前缀开头的明文密码需要在前面加 \ 转义，例如 \cmd:abc；
+# 留空的密码可在执行时通过 --ask-pass/-k 与 --ask-become-pass/-K 输入(不回显)
+# 10.0.5.17,admin,env:GOSS_ADMIN_PASS,file:~/.secrets/root_pass
+# 10.0.5.18,admin,cmd:pass show infra/admin,
+# 四列之后可追加 key=value 形式的扩展参数：
+#   identity_file  私钥文件路径(支持RSA/ECDSA/Ed25519，OpenSSH或PEM格式)，优先于密码认证
+#   passphrase     私钥保护密码
+#   certificate_file  OpenSSH用户证书路径，留空时自动使用私钥旁的 -cert.pub 文件
+#   jump           跳板机名称，对应goss_config.yaml中jump_hosts的定义，jump=none表示直连
+#   become_method  require_sudo任务的提权方式：su(默认)、sudo、doas、none，密码使用第四列
+#   become_user    提权的目标用户，默认root
+#   ciphers / key_exchanges / macs / host_key_algorithms  算法列表，使用 : 分隔，优先于全局配置
+# 10.0.5.18,deploy,,Root!789,identity_file=~/.ssh/id_ed25519
+# 10.0.5.19,deploy,,,identity_file=~/.ssh/id_rsa,passphrase=KeyP@ss
+# 10.0.5.20,deploy,,,identity_file=~/.ssh/id_ed25519,certificate_file=~/.ssh/id_ed25519-cert.pub
+# 10.10.0.21,deploy,Deploy123,,jump=bastion-inner
+# 10.10.0.22,deploy,Deploy123,Deploy123,become_method=sudo   # 禁止root登录的主机使用sudo，密码为deploy自身的密码
+# 只填写地址时，其余参数取自~/.ssh/config中匹配的Host块(HostName、User、Port、IdentityFile、ProxyJump等)，
+# 本文件中显式填写的值优先：
+# web-prod-01
+# web-prod-02,,,SudoP@ss!
+#
+# 需要分组与变量时可改用INI分组格式(文件中出现 [分组] 时自动识别)，或使用 .yaml/.yml 扩展名的YAML格式。
+# 变量中的 address port user password sudo_pass become_method become_user identity_file passphrase certificate_file jump
+# connect_timeout security_mode 以及算法列表会作为连接参数，其他变量(如 app_dir)
+# 可在任务中通过 {{ .Vars.变量名 }} 引用。优先级：主机变量 > 子分组变量 > 上级分组变量 > all
+# INI示例：
+# 10.0.0.9 user=root               # 第一个分组之前的主机属于ungrouped分组
+# [web]
+# web01 address=10.0.0.1 port=2222 app_dir=/opt/web
+# 10.0.0.2:2222
+# [web:vars]
+# user=deploy
+# become_method=sudo
+# [prod:children]
+# web
+# [prod:vars]
+# jump=bastion
+# YAML示例(hosts.yaml)：
+# all:
+#   vars:
+#     user: deploy
+#   children:
+#     web:
+#       vars:
+#         app_dir: /opt/web
+#       hosts:
+#         web01:
+#           address: 10.0.0.1
+#           port: 2222
+#         10.0.0.2:
 `
 
 const tasksTemplate = `# tasks.yaml
 # ============ 任务定义 ============
 # 支持四种任务类型：cmd, script, upload, download
 # 执行时按顺序执行 tasks 列表中的任务
-# 路径相关配置支持变量注入：{{ .IP }}或者{{ .TIME }} 
+# 路径相关配置支持变量注入：{{ .IP }}(主机清单中填写的名称，也可写作{{ .Name }})或者{{ .TIME }} 
 ## 例如: 路径./download/{{ .IP }}_{{ .TIME }}.txt程序会自动格式化最终展示为: ./download/192.168.200.2_20250403.txt
+# 主机清单中的变量可通过 {{ .Vars.变量名 }} 引用，所属分组为 {{ .Groups }}，引用不存在的变量时任务失败
+# cmd默认不做模板渲染，需要时设置 template: true
+# 采集主机信息(facts任务或goss_config.yaml中facts.gather)后可引用 {{ .Facts.OS.Family }}、{{ .Facts.Kernel.Arch }}、
+# {{ .Facts.CPUCount }}、{{ .Facts.Memory.TotalMB }}、{{ .Facts.PackageManager }} 等
 
 #tasks:
 #  # 1. 命令执行任务
@@ -71,7 +140,24 @@ const tasksTemplate = `# tasks.yaml
 #    description: "检查磁盘空间"  # 任务描述
 #    cmd: "df -h | grep -v tmpfs"  # 实际执行的命令
 #    require_sudo: false           # 是否使用特权用户执行
+#    become_method: sudo           # 提权方式 su/sudo/doas/none，留空使用主机清单中的设置(默认su)
+#    become_user: root             # 提权的目标用户，留空使用主机清单中的设置(默认root)
+#    forward_agent: false          # 是否将本地ssh-agent转发到远端（如远端需要git clone）
+#
+#  - type: cmd
+#    description: "重启应用"
+#    cmd: "systemctl restart {{ .Vars.app_name }}"
+#    template: true                # 使用主机变量渲染cmd
 #    
+#  - type: facts
+#    description: "采集主机信息"     # 之后的任务可引用 {{ .Facts }}
+#
+#  - type: cmd
+#    description: "安装依赖"
+#    cmd: "{{ if eq .Facts.OS.Family \"Debian\" }}apt-get install -y curl{{ else }}yum install -y curl{{ end }}"
+#    template: true
+#    require_sudo: true
+#
 #  # 2. 脚本执行任务
 #  - type: script
 #    description: "部署应用"
@@ -96,12 +182,106 @@ const tasksTemplate = `# tasks.yaml
 const goss_configTemplate = `connection:
   default_port: 22
   connect_timeout: 3
+  # 主机密钥校验模式：0 自动接受(仅限调试) 1 交互确认(非终端环境退化为严格模式) 2 严格模式 3 自动保存新主机密钥，拒绝冲突密钥
   security_mode: 0
+  # 交互确认主机密钥的等待时间(秒)，超时默认拒绝
+  hostkey_prompt_timeout: 30
+  # known_hosts文件路径，留空使用~/.ssh/known_hosts，可通过goss hostkeys scan预先填充
+  # 使用主机证书时只需添加CA公钥，例如：@cert-authority *.example.com ssh-ed25519 AAAA...
+  known_hosts_file: ""
+  # 默认私钥文件，主机未指定identity_file时使用
+  identity_files: []
+  # ssh客户端配置，主机会合并其中匹配的Host块，ProxyJump对应的跳板机自动生成，留空表示不使用
+  ssh_config_file: "~/.ssh/config"
+  # 加载主机清单时预先解析主机名，结果与模板中仍显示主机清单中的名称
+  # 经过跳板机的主机由跳板机解析；解析失败的主机不影响其他主机，连接时报告失败
+  resolve_hosts: false
+  # 是否使用SSH_AUTH_SOCK指向的ssh-agent认证
+  use_agent: true
+  # 默认跳板机，主机未指定jump时使用
+  default_jump: ""
+  # 连接池keepalive探测间隔(秒)，0表示不探测
+  keepalive_interval: 15
+  # 连接闲置回收时间(秒)，0表示不回收
+  idle_timeout: 300
+  # goss agent控制套接字，agent运行时exec/apply复用其持有的连接，留空表示不使用
+  control_path: "~/.goss/control.sock"
+  # goss agent中连接闲置保持时间(秒)
+  control_persist: 600
+  # 连接失败后的重试次数，拒绝连接、超时等网络错误会重试，认证失败与主机密钥错误不重试
+  connect_retries: 2
+  # 重试退避初始间隔(秒)，每次失败翻倍并加入随机抖动
+  retry_backoff: 1
+  # 重试退避最大间隔(秒)
+  retry_max_backoff: 10
+  # ssh算法策略，按优先级排列，留空使用默认算法，协商结果会记录在执行结果中
+  # ciphers: [chacha20-poly1305@openssh.com, aes256-gcm@openssh.com, aes128-ctr]
+  # key_exchanges: [curve25519-sha256, ecdh-sha2-nistp256]
+  # macs: [hmac-sha2-256-etm@openssh.com, hmac-sha2-256]
+  # host_key_algorithms: [ssh-ed25519, rsa-sha2-512]
+
+# 按主机覆盖算法策略(后定义的优先)，也可以在hosts文件中用 ciphers=a:b 等参数单独指定
+#algorithm_overrides:
+#  - hosts: ["10.20.*", "legacy-switch-*"]
+#    ciphers: [aes128-cbc, aes128-ctr]
+#    key_exchanges: [diffie-hellman-group14-sha1]
+#    host_key_algorithms: [ssh-rsa]
+
+# 跳板机定义，通过jump字段串联多级跳板，同一跳板机的连接被其后的所有主机共享
+#jump_hosts:
+#  bastion-outer:
+#    address: 203.0.113.10
+#    port: 22
+#    user: jump
+#    identity_file: ~/.ssh/id_ed25519
+#  bastion-inner:
+#    address: 10.10.0.1:2222
+#    user: jump
+#    password: JumpP@ss
+#    jump: bastion-outer
+
+# --hosts 指向可执行程序时作为动态清单执行(参数 --list)，标准输出为ansible格式的JSON清单：
+# {"web": {"hosts": ["web01"], "vars": {"user": "deploy"}, "children": []}, "_meta": {"hostvars": {"web01": {"address": "10.0.0.1"}}}}
+# 同样格式的静态 .json 文件(例如 goss inventory export 的输出)也可以直接作为 --hosts 使用
+inventory:
+  # 动态清单输出的缓存时间(秒)，0表示每次执行都重新获取
+  cache_ttl: 60
+  cache_dir: "~/.goss/inventory_cache"
+  # 动态清单程序的执行超时(秒)
+  script_timeout: 30
+  # --hosts 为 .xlsx 或带表头的 .csv 时按表头识别列，默认识别 ip/address、port、user、password、
+  # sudo password、group 等常见表头；表头不同时在此指定，goss inventory import 可转换为原生格式
+  # sheet: ""
+  # columns:
+  #   address: "管理IP"
+  #   port: "SSH端口"
+  #   user: "用户名"
+  #   password: "密码"
+  #   sudo_pass: "root密码"
+  #   groups: "业务组"
+
+# 主机信息采集，goss facts 或 facts 任务的结果按主机缓存
+facts:
+  # exec/apply 执行任务前自动采集
+  gather: false
+  # 缓存时间(秒)，0表示每次重新采集
+  cache_ttl: 3600
+  cache_dir: "~/.goss/facts_cache"
 
 execution:
   max_workers: 1
   task_timeout: 120
   stop_on_error: true
+  # 提权(require_sudo)时的密码提示识别，内置规则已覆盖 su/sudo/doas 的常见提示(含中文等本地化提示)
+  become:
+    # 输出停在无法识别的提示处(以 : 或 ? 结尾)超过该时间(秒)时报错，而不是等到task_timeout
+    prompt_timeout: 10
+    # 追加的密码提示正则，匹配输出最后一行的末尾
+    prompts: []
+    #  - "(?i)enter password for \\S+:"
+    # 追加的认证失败输出正则，输入密码后出现即判定密码错误
+    failures: []
+    #  - "(?i)access denied"
 
 file_transfer:
   default_upload_dir: "/tmp"
@@ -0,0 +1,324 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"goss/internal/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// inventoryCmd represents the inventory command
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Inspect, validate and export the host inventory.",
+	Long: `Show how goss understands the inventory given with --hosts: hosts and groups with resolved
+ports, users and variables (secrets masked), a group tree, a full validation report, exports
+to ini, yaml, json, csv or xlsx and imports from xlsx/csv host lists.`,
+}
+
+var inventoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List hosts and groups with resolved connection settings and variables.",
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := loadInventory()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		hosts := inv.Hosts
+		if limit, _ := cmd.Flags().GetString("limit"); limit != "" {
+			if hosts, err = config.FilterHosts(hosts, limit); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Host", "Address", "Port", "User", "Password", "Become Pass", "Identity File", "Jump", "Groups", "Vars"})
+		for _, h := range hosts {
+			t.AppendRow(table.Row{
+				h.DisplayName(), h.DialAddr(), h.Port, h.User,
+				config.MaskSecret(h.Password), config.MaskSecret(h.SudoPass),
+				h.IdentityFile, h.Jump, strings.Join(inv.DirectGroups(h.Name), ","), formatVars(h.Vars),
+			})
+		}
+		t.Render()
+		if hostsOnly, _ := cmd.Flags().GetBool("hosts-only"); hostsOnly {
+			return
+		}
+		g := table.NewWriter()
+		g.SetOutputMirror(os.Stdout)
+		g.AppendHeader(table.Row{"Group", "Hosts", "Children", "Vars"})
+		for _, name := range inv.SortedGroups() {
+			group := inv.Groups[name]
+			g.AppendRow(table.Row{name, len(group.Hosts), strings.Join(group.Children, ","), formatVars(group.Vars)})
+		}
+		g.Render()
+	},
+}
+
+var inventoryValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the inventory for syntax errors, duplicates, bad ports and missing credentials.",
+	Long: `Load the inventory and report every problem found with its line number instead of stopping
+at the first one. Exits with status 1 when the inventory is not valid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(ConfigPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		var errs config.InventoryErrors
+		collect := func(err error) {
+			var list config.InventoryErrors
+			if errors.As(err, &list) {
+				errs = append(errs, list...)
+			} else if err != nil {
+				errs = append(errs, &config.InventoryError{Msg: err.Error()})
+			}
+		}
+		inv, err := config.LoadInventory(HostPath, cfg.Inventory)
+		collect(err)
+		if inv != nil {
+			collect(cfg.CompleteHosts(inv.Hosts))
+			collect(cfg.ResolveSecrets(inv.Hosts))
+			collect(cfg.CheckHosts(inv.Hosts))
+		}
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		for _, e := range errs {
+			fmt.Printf("%s: %s\n", HostPath, e)
+		}
+		if len(errs) > 0 {
+			fmt.Printf("%s is not valid: %d error(s)\n", HostPath, len(errs))
+			// 便于在CI中使用，校验失败时返回非0状态码
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid: %d host(s), %d group(s)\n", HostPath, len(inv.Hosts), len(inv.Groups))
+	},
+}
+
+var inventoryGraphCmd = &cobra.Command{
+	Use:   "graph [group]",
+	Short: "Print the group tree with its hosts.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := loadInventory()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		root := config.GroupAll
+		if len(args) == 1 {
+			root = args[0]
+		}
+		if _, ok := inv.Groups[root]; !ok {
+			fmt.Printf("group %s is not defined in the inventory\n", root)
+			return
+		}
+		printGroupTree(inv, root, "")
+	},
+}
+
+var inventoryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the resolved inventory as ini, yaml, json, csv or xlsx.",
+	Long: `Export the inventory after group variables, ~/.ssh/config and defaults have been applied.
+The json format is the dynamic inventory format and can be loaded back with --hosts.
+Passwords and passphrases are left out unless --include-secrets is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+		if format == "" {
+			format = strings.TrimPrefix(filepath.Ext(output), ".")
+			if format == "yml" {
+				format = config.ExportYAML
+			}
+		}
+		if format == "" {
+			format = config.ExportJSON
+		}
+		if format == config.ExportXLSX && output == "" {
+			fmt.Println("xlsx export requires --output")
+			return
+		}
+		inv, err := loadInventory()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := inv.Export(&buf, format, includeSecrets); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if output == "" {
+			os.Stdout.Write(buf.Bytes())
+			return
+		}
+		// 包含密码时仅当前用户可读
+		mode := os.FileMode(0644)
+		if includeSecrets {
+			mode = 0600
+		}
+		if err := os.WriteFile(output, buf.Bytes(), mode); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Exported %d host(s) to %s\n", len(inv.Hosts), output)
+	},
+}
+
+var inventoryImportCmd = &cobra.Command{
+	Use:   "import <file.xlsx|file.csv>",
+	Short: "Convert an xlsx/csv host list into the native ini or yaml inventory.",
+	Long: `Read a spreadsheet whose first row is a header and convert it into the native inventory format.
+Columns are matched by header (ip/address, port, user, password, sudo password, group, ...), or
+mapped explicitly with inventory.columns in goss_config.yaml or --column address="Mgmt IP".
+Rows that cannot be imported are reported with their row number and left out of the result.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		sheet, _ := cmd.Flags().GetString("sheet")
+		mapping, _ := cmd.Flags().GetStringToString("column")
+		if format == "" {
+			format = config.ExportINI
+			if ext := strings.TrimPrefix(filepath.Ext(output), "."); ext == "yaml" || ext == "yml" {
+				format = config.ExportYAML
+			}
+		}
+		if format != config.ExportINI && format != config.ExportYAML {
+			fmt.Printf("unsupported import format %q, expected ini or yaml\n", format)
+			return
+		}
+		cfg, err := config.LoadConfig(ConfigPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		opts := *cfg.Inventory
+		if sheet != "" {
+			opts.Sheet = sheet
+		}
+		// 命令行指定的列映射优先于配置文件
+		opts.Columns = make(map[string]string)
+		for k, v := range cfg.Inventory.Columns {
+			opts.Columns[k] = v
+		}
+		for k, v := range mapping {
+			opts.Columns[k] = v
+		}
+		inv, rejected, err := config.ImportSheet(args[0], &opts)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := inv.Export(&buf, format, true); err != nil {
+			fmt.Println(err)
+			return
+		}
+		// 输出到标准输出时报告写入标准错误，避免混入清单内容
+		report := os.Stdout
+		if output == "" {
+			os.Stdout.Write(buf.Bytes())
+			report = os.Stderr
+		} else if err := os.WriteFile(output, buf.Bytes(), 0600); err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, e := range rejected {
+			fmt.Fprintf(report, "rejected row %d: %s\n", e.Line, e.Msg)
+		}
+		fmt.Fprintf(report, "Imported %d host(s) from %s, %d row(s) rejected\n", len(inv.Hosts), args[0], len(rejected))
+		if inventoryHasPasswords(inv) {
+			fmt.Fprintln(report, "Passwords are stored in plain text, consider 'goss vault encrypt' on the result")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inventoryCmd)
+	inventoryCmd.AddCommand(inventoryListCmd, inventoryValidateCmd, inventoryGraphCmd, inventoryExportCmd, inventoryImportCmd)
+	inventoryListCmd.Flags().StringP("limit", "l", "", "Only list hosts matching the pattern, same syntax as exec/apply --limit")
+	inventoryListCmd.Flags().Bool("hosts-only", false, "Do not print the group table")
+	inventoryExportCmd.Flags().StringP("format", "f", "", "Export format: ini, yaml, json, csv or xlsx (default: from the --output extension, otherwise json)")
+	inventoryExportCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+	inventoryExportCmd.Flags().Bool("include-secrets", false, "Include passwords, become passwords and passphrases in the export")
+	inventoryImportCmd.Flags().StringP("format", "f", "", "Output format: ini or yaml (default: yaml for a .yaml/.yml --output, otherwise ini)")
+	inventoryImportCmd.Flags().StringP("output", "o", "", "Write the inventory to a file instead of stdout")
+	inventoryImportCmd.Flags().String("sheet", "", "Worksheet to read from an xlsx file (default: the first sheet)")
+	inventoryImportCmd.Flags().StringToString("column", nil, "Map a field to a header, e.g. --column address=\"Mgmt IP\" --column groups=Env (fields: name, address, port, user, password, sudo_pass, groups, identity_file, jump)")
+}
+
+// loadInventory 加载主机清单并补全端口、用户等连接参数
+func loadInventory() (*config.Inventory, error) {
+	cfg, err := config.LoadConfig(ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := config.LoadInventory(HostPath, cfg.Inventory)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.CompleteHosts(inv.Hosts); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func inventoryHasPasswords(inv *config.Inventory) bool {
+	for _, h := range inv.Hosts {
+		if h.Password != "" || h.SudoPass != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatVars 按变量名排序输出 k=v，敏感变量显示为掩码
+func formatVars(vars map[string]any) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := fmt.Sprint(vars[k])
+		if config.IsSecretVar(k) {
+			value = config.MaskSecret(value)
+		}
+		pairs = append(pairs, k+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// printGroupTree 以树形打印分组，all下的主机已归入ungrouped或其他分组，不重复打印
+func printGroupTree(inv *config.Inventory, name, indent string) {
+	if indent == "" {
+		fmt.Printf("@%s:\n", name)
+	}
+	group := inv.Groups[name]
+	for _, child := range group.Children {
+		fmt.Printf("%s  |--@%s:\n", indent, child)
+		printGroupTree(inv, child, indent+"  |")
+	}
+	if name == config.GroupAll {
+		return
+	}
+	for _, host := range group.Hosts {
+		fmt.Printf("%s  |--%s\n", indent, host)
+	}
+}
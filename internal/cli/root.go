@@ -4,8 +4,13 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cli
 
 import (
+	"bytes"
+	"fmt"
 	"goss/internal/config"
+	"goss/internal/utils"
 	"os"
+	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -14,6 +19,11 @@ var (
 	Save       string
 	HostPath   string
 	ConfigPath string
+	// vault口令文件，为空时在遇到加密内容时提示输入
+	VaultPasswordFile string
+	// 执行前提示输入登录密码与特权密码，用于主机清单中未填写密码的主机
+	AskPass       bool
+	AskBecomePass bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -43,20 +53,126 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().StringVar(&Save, "save", "", "The output format supports (json, excel).")
 	rootCmd.PersistentFlags().StringVar(&ConfigPath, "config", "goss_config.yaml", "Specify the location of goss environment variables. A template configuration can be generated using the init subcommand.")
-	rootCmd.PersistentFlags().StringVar(&HostPath, "hosts", "hosts.ini", "Host information configuration file path")
+	rootCmd.PersistentFlags().StringVar(&VaultPasswordFile, "vault-password-file", "", "File containing the vault password, or an executable that prints it. Prompted for when omitted and encrypted content is found")
+	rootCmd.PersistentFlags().BoolVarP(&AskPass, "ask-pass", "k", false, "Prompt for the SSH login password used by hosts without one in the inventory")
+	rootCmd.PersistentFlags().BoolVarP(&AskBecomePass, "ask-become-pass", "K", false, "Prompt for the privilege escalation password used by hosts without one in the inventory")
+	rootCmd.PersistentFlags().StringVar(&HostPath, "hosts", "hosts.ini", "Host inventory path: a CSV/INI/YAML file or an executable that prints a JSON inventory")
+	// 口令在加载到加密内容时才读取，此时命令行参数已解析
+	config.SetVaultPassword(func() ([]byte, error) {
+		return readVaultPassword(VaultPasswordFile, "Vault password: ")
+	})
+}
+
+// readVaultPassword 读取vault口令，可执行的口令文件取其标准输出，未指定文件时在终端提示输入
+func readVaultPassword(file, prompt string) ([]byte, error) {
+	if file == "" {
+		password, err := utils.ReadPassword(prompt)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(password), nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	if info.Mode().Perm()&0111 != 0 {
+		content, err = exec.Command(file).Output()
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault password file %s: %w", file, err)
+	}
+	password := bytes.TrimRight(content, "\r\n")
+	if len(password) == 0 {
+		return nil, fmt.Errorf("vault password file %s is empty", file)
+	}
+	return password, nil
 }
 
 // 基础配置解析器负责解析cli全局配置和主机信息配置
 func basicConfigurationParserconfigParser(HostPath string) ([]*config.Host, *config.GossConfig, error) {
-	// 解析hosts配置
-	hosts, err := config.ParseHostsFile(HostPath)
+	// 解析全局配置
+	cfg, err := config.LoadConfig(ConfigPath)
 	if err != nil {
 		return nil, nil, err
 	}
-	// 解析全局配置
-	cfg, err := config.LoadConfig(ConfigPath)
+	// 解析hosts配置，动态清单的缓存与超时取自全局配置
+	hosts, err := config.ParseHostsFile(HostPath, cfg.Inventory)
 	if err != nil {
 		return nil, nil, err
 	}
+	// 合并~/.ssh/config并填充默认端口与用户
+	if err := cfg.CompleteHosts(hosts); err != nil {
+		return nil, nil, err
+	}
 	return hosts, cfg, nil
 }
+
+// prepareHosts 解析选中主机的密码引用，并按 --ask-pass/--ask-become-pass 提示输入密码，
+// 在 --limit 筛选之后调用
+func prepareHosts(cfg *config.GossConfig, hosts []*config.Host) error {
+	if err := cfg.ResolveSecrets(hosts); err != nil {
+		return err
+	}
+	return askPasswords(hosts)
+}
+
+// addLimitFlags 为执行任务的子命令添加主机筛选参数
+func addLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("limit", "l", "", "Select hosts by group, name, glob or ~regex; use ',' to combine, '&' to intersect, '!' to exclude and @file to read patterns from a file")
+	cmd.Flags().Bool("list-hosts", false, "Print the selected hosts without connecting")
+}
+
+// selectHosts 按 --limit 筛选主机，指定 --list-hosts 时打印主机列表并返回true
+func selectHosts(cmd *cobra.Command, hosts []*config.Host) ([]*config.Host, bool, error) {
+	limit, err := cmd.Flags().GetString("limit")
+	if err != nil {
+		return nil, false, err
+	}
+	if limit != "" {
+		if hosts, err = config.FilterHosts(hosts, limit); err != nil {
+			return nil, false, err
+		}
+	}
+	listOnly, err := cmd.Flags().GetBool("list-hosts")
+	if err != nil {
+		return nil, false, err
+	}
+	if listOnly {
+		fmt.Printf("hosts (%d):\n", len(hosts))
+		for _, host := range hosts {
+			fmt.Printf("  %s\t%s:%s\t%s\n", host.DisplayName(), host.DialAddr(), host.Port, strings.Join(host.Groups, ","))
+		}
+	}
+	return hosts, listOnly, nil
+}
+
+// askPasswords 按 --ask-pass/--ask-become-pass 提示输入密码，主机清单中已有的密码优先
+func askPasswords(hosts []*config.Host) error {
+	if AskPass {
+		password, err := utils.ReadPassword("SSH password: ")
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			if host.Password == "" {
+				host.Password = password
+			}
+		}
+	}
+	if AskBecomePass {
+		password, err := utils.ReadPassword("BECOME password: ")
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			if host.SudoPass == "" {
+				host.SudoPass = password
+			}
+		}
+	}
+	return nil
+}
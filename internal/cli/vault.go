@@ -0,0 +1,315 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"goss/internal/vault"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// vaultCmd represents the vault command
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Encrypt and decrypt inventory, task files and individual secrets.",
+	Long: `Protect passwords stored in hosts.ini, tasks.yml or goss_config.yaml with a passphrase.
+Whole files or individual values can be encrypted (argon2id + AES-256-GCM). Encrypted content
+is decrypted transparently by exec/apply with --vault-password-file or an interactive prompt.`,
+}
+
+var vaultEncryptCmd = &cobra.Command{
+	Use:   "encrypt [file...]",
+	Short: "Encrypt files in place, or print encrypted values with --value.",
+	Run: func(cmd *cobra.Command, args []string) {
+		values, _ := cmd.Flags().GetStringArray("value")
+		if len(args) == 0 && len(values) == 0 {
+			fmt.Println("no file or --value given")
+			return
+		}
+		keyring, err := newVaultKeyring(VaultPasswordFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, v := range values {
+			encrypted, err := keyring.EncryptValue(v)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Println(encrypted)
+		}
+		for _, file := range args {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if vault.IsEncrypted(content) {
+				fmt.Printf("%s is already encrypted\n", file)
+				return
+			}
+			encrypted, err := keyring.Encrypt(content)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := writeFileKeepMode(file, encrypted); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("Encrypted %s\n", file)
+		}
+	},
+}
+
+var vaultDecryptCmd = &cobra.Command{
+	Use:   "decrypt [file...]",
+	Short: "Decrypt files in place, or print decrypted values with --value.",
+	Run: func(cmd *cobra.Command, args []string) {
+		values, _ := cmd.Flags().GetStringArray("value")
+		toStdout, _ := cmd.Flags().GetBool("stdout")
+		if len(args) == 0 && len(values) == 0 {
+			fmt.Println("no file or --value given")
+			return
+		}
+		keyring, err := openVaultKeyring(VaultPasswordFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, v := range values {
+			plain, err := keyring.DecryptValue(strings.TrimSpace(v))
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Println(plain)
+		}
+		for _, file := range args {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if !vault.IsEncrypted(content) {
+				fmt.Printf("%s is not vault encrypted\n", file)
+				return
+			}
+			plain, err := keyring.Decrypt(content)
+			if err != nil {
+				fmt.Printf("%s: %s\n", file, err)
+				return
+			}
+			if toStdout {
+				os.Stdout.Write(plain)
+				continue
+			}
+			if err := writeFileKeepMode(file, plain); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("Decrypted %s\n", file)
+		}
+	},
+}
+
+var vaultEditCmd = &cobra.Command{
+	Use:   "edit <file>",
+	Short: "Edit an encrypted file with $EDITOR, creating it when it does not exist.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+		var (
+			plain   []byte
+			keyring *vault.Keyring
+		)
+		content, err := os.ReadFile(file)
+		switch {
+		case os.IsNotExist(err):
+			keyring, err = newVaultKeyring(VaultPasswordFile)
+		case err != nil:
+		case !vault.IsEncrypted(content):
+			err = fmt.Errorf("%s is not vault encrypted, use 'goss vault encrypt' first", file)
+		default:
+			keyring, err = openVaultKeyring(VaultPasswordFile)
+			if err == nil {
+				plain, err = keyring.Decrypt(content)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		edited, err := editInTempFile(file, plain)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if content != nil && bytes.Equal(edited, plain) {
+			fmt.Println("No changes, file is left untouched")
+			return
+		}
+		encrypted, err := keyring.Encrypt(edited)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := writeFileKeepMode(file, encrypted); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Saved %s\n", file)
+	},
+}
+
+var vaultRekeyCmd = &cobra.Command{
+	Use:   "rekey <file...>",
+	Short: "Re-encrypt files and the encrypted values inside them with a new password.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		oldKeyring, err := openVaultKeyring(VaultPasswordFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		newPasswordFile, _ := cmd.Flags().GetString("new-vault-password-file")
+		newKeyring, err := newVaultKeyring(newPasswordFile)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		// 先全部解密再写入，任一文件口令错误时不修改任何文件
+		rekeyed := make([][]byte, len(args))
+		for i, file := range args {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			switch {
+			case vault.IsEncrypted(content):
+				plain, err := oldKeyring.Decrypt(content)
+				if err == nil {
+					rekeyed[i], err = newKeyring.Encrypt(plain)
+				}
+				if err != nil {
+					fmt.Printf("%s: %s\n", file, err)
+					return
+				}
+			case vault.ContainsEncryptedValues(content):
+				rekeyed[i], err = vault.ReplaceValues(content, func(value string) (string, error) {
+					plain, err := oldKeyring.DecryptValue(value)
+					if err != nil {
+						return "", err
+					}
+					return newKeyring.EncryptValue(plain)
+				})
+				if err != nil {
+					fmt.Printf("%s: %s\n", file, err)
+					return
+				}
+			default:
+				fmt.Printf("%s does not contain vault encrypted content\n", file)
+				return
+			}
+		}
+		for i, file := range args {
+			if err := writeFileKeepMode(file, rekeyed[i]); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("Rekeyed %s\n", file)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultEncryptCmd, vaultDecryptCmd, vaultEditCmd, vaultRekeyCmd)
+	vaultEncryptCmd.Flags().StringArray("value", nil, "Encrypt a single value and print it, e.g. to paste into hosts.ini (repeatable)")
+	vaultDecryptCmd.Flags().StringArray("value", nil, "Decrypt a single encrypted value and print it (repeatable)")
+	vaultDecryptCmd.Flags().Bool("stdout", false, "Print decrypted files instead of writing them in place")
+	vaultRekeyCmd.Flags().String("new-vault-password-file", "", "File containing the new vault password (prompted for when omitted)")
+}
+
+// openVaultKeyring 读取用于解密的已有口令
+func openVaultKeyring(passwordFile string) (*vault.Keyring, error) {
+	password, err := readVaultPassword(passwordFile, "Vault password: ")
+	if err != nil {
+		return nil, err
+	}
+	return vault.NewKeyring(password)
+}
+
+// newVaultKeyring 读取用于加密的新口令，在终端输入时需要确认一次
+func newVaultKeyring(passwordFile string) (*vault.Keyring, error) {
+	password, err := readVaultPassword(passwordFile, "New vault password: ")
+	if err != nil {
+		return nil, err
+	}
+	if passwordFile == "" {
+		confirm, err := readVaultPassword("", "Confirm new vault password: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(password, confirm) {
+			return nil, fmt.Errorf("passwords do not match")
+		}
+	}
+	return vault.NewKeyring(password)
+}
+
+// editInTempFile 将明文写入仅当前用户可访问的临时目录并打开编辑器，返回编辑后的内容
+func editInTempFile(file string, content []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "goss-vault-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	// 保留扩展名便于编辑器识别语法
+	tmp := filepath.Join(dir, filepath.Base(file))
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return nil, err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// 编辑器变量可以携带参数，例如 "code --wait"
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", tmp)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+	return os.ReadFile(tmp)
+}
+
+// writeFileKeepMode 通过临时文件原子替换，保留原文件权限
+func writeFileKeepMode(file string, content []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := file + ".goss-tmp"
+	if err := os.WriteFile(tmp, content, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
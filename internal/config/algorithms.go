@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AlgorithmPolicy ssh算法策略，列表按优先级排列，为空时使用ssh库的默认算法
+type AlgorithmPolicy struct {
+	Ciphers           []string `mapstructure:"ciphers"`
+	KeyExchanges      []string `mapstructure:"key_exchanges"`
+	MACs              []string `mapstructure:"macs"`
+	HostKeyAlgorithms []string `mapstructure:"host_key_algorithms"`
+}
+
+// AlgorithmOverride 对匹配的主机覆盖全局算法策略，hosts支持 * ? 通配符
+type AlgorithmOverride struct {
+	Hosts           []string `mapstructure:"hosts"`
+	AlgorithmPolicy `mapstructure:",squash"`
+}
+
+// merge 用other中已配置的列表覆盖当前策略
+func (p AlgorithmPolicy) merge(other AlgorithmPolicy) AlgorithmPolicy {
+	if len(other.Ciphers) > 0 {
+		p.Ciphers = other.Ciphers
+	}
+	if len(other.KeyExchanges) > 0 {
+		p.KeyExchanges = other.KeyExchanges
+	}
+	if len(other.MACs) > 0 {
+		p.MACs = other.MACs
+	}
+	if len(other.HostKeyAlgorithms) > 0 {
+		p.HostKeyAlgorithms = other.HostKeyAlgorithms
+	}
+	return p
+}
+
+// HostAlgorithms 计算主机最终使用的算法策略，优先级：主机配置 > algorithm_overrides(后定义的优先) > connection
+// names为主机的名称与地址，任一匹配即应用覆盖项
+func (cfg *GossConfig) HostAlgorithms(explicit AlgorithmPolicy, names ...string) AlgorithmPolicy {
+	policy := cfg.Connection.AlgorithmPolicy
+	for _, o := range cfg.AlgorithmOverrides {
+		if o.matches(names) {
+			policy = policy.merge(o.AlgorithmPolicy)
+		}
+	}
+	return policy.merge(explicit)
+}
+
+func (o *AlgorithmOverride) matches(names []string) bool {
+	for _, pattern := range o.Hosts {
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validate 校验算法名称是否受支持，启用已知不安全的算法时给出警告
+func (p AlgorithmPolicy) validate() error {
+	supported := ssh.SupportedAlgorithms()
+	insecure := ssh.InsecureAlgorithms()
+	checks := []struct {
+		key      string
+		names    []string
+		secure   []string
+		insecure []string
+	}{
+		{"ciphers", p.Ciphers, supported.Ciphers, insecure.Ciphers},
+		{"key_exchanges", p.KeyExchanges, supported.KeyExchanges, insecure.KeyExchanges},
+		{"macs", p.MACs, supported.MACs, insecure.MACs},
+		// 证书算法同样可以用于主机密钥
+		{"host_key_algorithms", p.HostKeyAlgorithms, supported.HostKeys, insecure.HostKeys},
+	}
+	for _, c := range checks {
+		for _, name := range c.names {
+			switch {
+			case slices.Contains(c.secure, name):
+			case slices.Contains(c.insecure, name):
+				slog.Warn("Insecure SSH algorithm enabled", "setting", c.key, "algorithm", name)
+			default:
+				return fmt.Errorf("%s: unsupported algorithm %q, supported: %s", c.key, name, strings.Join(append(c.secure, c.insecure...), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validateAlgorithms 校验全局与各覆盖项的算法策略
+func validateAlgorithms(cfg *GossConfig) error {
+	if err := cfg.Connection.AlgorithmPolicy.validate(); err != nil {
+		return err
+	}
+	for i, o := range cfg.AlgorithmOverrides {
+		if o == nil || len(o.Hosts) == 0 {
+			return fmt.Errorf("algorithm_overrides[%d]: hosts is required", i)
+		}
+		for _, pattern := range o.Hosts {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("algorithm_overrides[%d]: invalid host pattern %q", i, pattern)
+			}
+		}
+		if err := o.AlgorithmPolicy.validate(); err != nil {
+			return fmt.Errorf("algorithm_overrides[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"goss/pkg/easyssh"
 	"os"
 
 	"github.com/spf13/viper"
@@ -17,13 +18,34 @@ const (
 // 默认值常量定义
 const (
 	// Connection 默认值
-	DefaultPort           = 22
-	DefaultConnectTimeout = 3
-	DefaultSecurityMode   = 0
+	DefaultPort            = 22
+	DefaultConnectTimeout  = 3
+	DefaultSecurityMode    = 0
+	DefaultUseAgent        = true
+	DefaultKeepalive       = 15
+	DefaultIdleTimeout     = 300
+	DefaultControlPath     = "~/.goss/control.sock"
+	DefaultControlPersist  = 600
+	DefaultConnectRetries  = 2
+	DefaultRetryBackoff    = 1.0
+	DefaultRetryMaxBackoff = 10.0
+	DefaultPromptTimeout   = 30
+	DefaultSSHConfigFile   = "~/.ssh/config"
+
+	// Inventory 默认值
+	DefaultInventoryCacheTTL      = 60
+	DefaultInventoryCacheDir      = "~/.goss/inventory_cache"
+	DefaultInventoryScriptTimeout = 30
+
+	// Facts 默认值
+	DefaultFactsCacheTTL = 3600
+	DefaultFactsCacheDir = "~/.goss/facts_cache"
 
 	// Execution 默认值
 	DefaultMaxWorkers  = 1
 	DefaultTaskTimeout = 120
+	// 提权程序停在无法识别的提示处的等待时间(秒)
+	DefaultBecomePromptTimeout = 10
 
 	// FileTransfer 默认值
 	DefaultUploadDir       = "/tmp"
@@ -38,18 +60,93 @@ type GossConfig struct {
 	Connection   *ConnectionConfig   `mapstructure:"connection"`
 	Execution    *ExecutionConfig    `mapstructure:"execution"`
 	FileTransfer *FileTransferConfig `mapstructure:"file_transfer"`
+	Inventory    *InventoryConfig    `mapstructure:"inventory"`
+	Facts        *FactsConfig        `mapstructure:"facts"`
+	// 跳板机定义，key为跳板机名称，主机通过 jump=名称 引用
+	JumpHosts map[string]*JumpHost `mapstructure:"jump_hosts"`
+	// 按主机覆盖全局算法策略，例如只支持旧算法的网络设备
+	AlgorithmOverrides []*AlgorithmOverride `mapstructure:"algorithm_overrides"`
+
+	// connection.ssh_config_file 解析后的ssh客户端配置
+	sshConfig *SSHConfig
+}
+
+// JumpHost 跳板机配置，每一跳使用独立的认证信息与主机密钥校验
+type JumpHost struct {
+	Address      string `mapstructure:"address"`
+	Port         int    `mapstructure:"port"`
+	User         string `mapstructure:"user"`
+	Password     string `mapstructure:"password"`
+	IdentityFile string `mapstructure:"identity_file"`
+	Passphrase   string `mapstructure:"passphrase"`
+	// OpenSSH用户证书路径
+	CertificateFile string `mapstructure:"certificate_file"`
+	// 上一跳跳板机名称，用于构建多级跳板链
+	Jump string `mapstructure:"jump"`
 }
 
 type ConnectionConfig struct {
 	DefaultPort    int `mapstructure:"default_port"`
 	ConnectTimeout int `mapstructure:"connect_timeout"`
 	SecurityMode   int `mapstructure:"security_mode"`
+	// 默认私钥文件，主机未指定identity_file时使用
+	IdentityFiles []string `mapstructure:"identity_files"`
+	// 是否通过SSH_AUTH_SOCK指向的ssh-agent进行认证
+	UseAgent bool `mapstructure:"use_agent"`
+	// 默认跳板机名称，主机未指定jump时使用
+	DefaultJump string `mapstructure:"default_jump"`
+	// 连接池keepalive探测间隔(秒)，0表示不探测
+	KeepaliveInterval int `mapstructure:"keepalive_interval"`
+	// 连接闲置超过该时间(秒)后被回收，0表示不回收
+	IdleTimeout int `mapstructure:"idle_timeout"`
+	// goss agent控制套接字路径，后台进程运行时exec/apply复用其连接，为空表示不使用
+	ControlPath string `mapstructure:"control_path"`
+	// goss agent中连接闲置保持时间(秒)
+	ControlPersist int `mapstructure:"control_persist"`
+	// 连接失败后的重试次数，认证失败与主机密钥错误不重试
+	ConnectRetries int `mapstructure:"connect_retries"`
+	// 重试退避的初始间隔(秒)，每次失败后翻倍
+	RetryBackoff float64 `mapstructure:"retry_backoff"`
+	// 重试退避的最大间隔(秒)
+	RetryMaxBackoff float64 `mapstructure:"retry_max_backoff"`
+	// 交互模式下等待确认主机密钥的时间(秒)，超时默认拒绝
+	HostKeyPromptTimeout int `mapstructure:"hostkey_prompt_timeout"`
+	// known_hosts文件路径，为空时使用~/.ssh/known_hosts
+	KnownHostsFile string `mapstructure:"known_hosts_file"`
+	// ssh客户端配置文件，主机清单中的主机会合并其中匹配的Host块，为空表示不使用
+	SSHConfigFile string `mapstructure:"ssh_config_file"`
+	// 加载主机清单时解析主机名，结果与模板中仍显示原始名称
+	ResolveHosts bool `mapstructure:"resolve_hosts"`
+	// ssh算法策略：ciphers、key_exchanges、macs、host_key_algorithms
+	AlgorithmPolicy `mapstructure:",squash"`
+}
+
+// FactsConfig 主机信息采集配置
+type FactsConfig struct {
+	// exec/apply 在执行任务前采集主机信息，模板中可通过 {{ .Facts.OS.Family }} 引用
+	Gather bool `mapstructure:"gather"`
+	// 采集结果的缓存时间(秒)，0表示每次都重新采集
+	CacheTTL int `mapstructure:"cache_ttl"`
+	// 采集结果缓存目录，每个主机一个文件
+	CacheDir string `mapstructure:"cache_dir"`
 }
 
 type ExecutionConfig struct {
 	MaxWorkers  int  `mapstructure:"max_workers"`
 	TaskTimeout int  `mapstructure:"task_timeout"`
 	StopOnError bool `mapstructure:"stop_on_error"`
+	// 提权时的密码提示识别规则
+	Become BecomeConfig `mapstructure:"become"`
+}
+
+// BecomeConfig 在内置规则之外追加的提示与失败输出(正则表达式)，对所有提权方式生效
+type BecomeConfig struct {
+	// 输出停在疑似提示但无法识别的行超过该时间(秒)时报错
+	PromptTimeout int `mapstructure:"prompt_timeout"`
+	// 密码提示，匹配输出最后一行的末尾，例如 "(?i)enter password for \\S+:"
+	Prompts []string `mapstructure:"prompts"`
+	// 输入密码后出现即视为密码错误
+	Failures []string `mapstructure:"failures"`
 }
 
 type FileTransferConfig struct {
@@ -67,8 +164,24 @@ func LoadConfig(path string) (*GossConfig, error) {
 	v.SetDefault("connection.default_port", DefaultPort)
 	v.SetDefault("connection.connect_timeout", DefaultConnectTimeout)
 	v.SetDefault("connection.security_mode", DefaultSecurityMode)
+	v.SetDefault("connection.use_agent", DefaultUseAgent)
+	v.SetDefault("connection.keepalive_interval", DefaultKeepalive)
+	v.SetDefault("connection.idle_timeout", DefaultIdleTimeout)
+	v.SetDefault("connection.control_path", DefaultControlPath)
+	v.SetDefault("connection.control_persist", DefaultControlPersist)
+	v.SetDefault("connection.connect_retries", DefaultConnectRetries)
+	v.SetDefault("connection.retry_backoff", DefaultRetryBackoff)
+	v.SetDefault("connection.retry_max_backoff", DefaultRetryMaxBackoff)
+	v.SetDefault("connection.hostkey_prompt_timeout", DefaultPromptTimeout)
+	v.SetDefault("connection.ssh_config_file", DefaultSSHConfigFile)
+	v.SetDefault("inventory.cache_ttl", DefaultInventoryCacheTTL)
+	v.SetDefault("inventory.cache_dir", DefaultInventoryCacheDir)
+	v.SetDefault("inventory.script_timeout", DefaultInventoryScriptTimeout)
+	v.SetDefault("facts.cache_ttl", DefaultFactsCacheTTL)
+	v.SetDefault("facts.cache_dir", DefaultFactsCacheDir)
 	v.SetDefault("execution.max_workers", DefaultMaxWorkers)
 	v.SetDefault("execution.task_timeout", DefaultTaskTimeout)
+	v.SetDefault("execution.become.prompt_timeout", DefaultBecomePromptTimeout)
 	v.SetDefault("file_transfer.default_upload_dir", DefaultUploadDir)
 	v.SetDefault("file_transfer.default_download_dir", DefaultDownloadDir)
 	v.SetDefault("file_transfer.overwrite_policy", DefaultOverwritePolicy)
@@ -87,6 +200,26 @@ func LoadConfig(path string) (*GossConfig, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse goss configuration: %s", err.Error())
 	}
+	// 跳板机密码支持vault加密值，env: file: cmd: 引用在ResolveSecrets中解析
+	for name, jump := range cfg.JumpHosts {
+		if jump == nil {
+			continue
+		}
+		if err := decryptValues(&jump.Password, &jump.Passphrase); err != nil {
+			return nil, fmt.Errorf("jump host %s: %s", name, err.Error())
+		}
+		// 未配置端口时使用默认端口
+		if jump.Port == 0 {
+			jump.Port = cfg.Connection.DefaultPort
+		}
+	}
+	if cfg.Connection.SSHConfigFile != "" {
+		sshConfig, err := LoadSSHConfig(cfg.Connection.SSHConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh config: %s", err.Error())
+		}
+		cfg.sshConfig = sshConfig
+	}
 	if err := ValidateConfig(&cfg); err != nil {
 		return nil, fmt.Errorf("goss configuration validation failed: %s", err.Error())
 	}
@@ -104,9 +237,48 @@ func ValidateConfig(cfg *GossConfig) error {
 		return fmt.Errorf("connect_timeout must be greater than 0")
 	}
 
-	if cfg.Connection.SecurityMode < 0 || cfg.Connection.SecurityMode > 2 {
-		return fmt.Errorf("security_mode must be between 0 and 2")
+	if cfg.Connection.SecurityMode < 0 || cfg.Connection.SecurityMode > 3 {
+		return fmt.Errorf("security_mode must be between 0 and 3")
+	}
+
+	if cfg.Connection.KeepaliveInterval < 0 {
+		return fmt.Errorf("keepalive_interval must not be negative")
+	}
+
+	if cfg.Connection.IdleTimeout < 0 {
+		return fmt.Errorf("idle_timeout must not be negative")
+	}
+
+	if cfg.Connection.ControlPersist < 0 {
+		return fmt.Errorf("control_persist must not be negative")
+	}
+
+	if cfg.Connection.ConnectRetries < 0 {
+		return fmt.Errorf("connect_retries must not be negative")
+	}
+
+	if cfg.Connection.RetryBackoff < 0 || cfg.Connection.RetryMaxBackoff < cfg.Connection.RetryBackoff {
+		return fmt.Errorf("retry_backoff must not be negative and must not exceed retry_max_backoff")
+	}
+
+	if cfg.Connection.HostKeyPromptTimeout <= 0 {
+		return fmt.Errorf("hostkey_prompt_timeout must be greater than 0")
+	}
+	if cfg.Inventory.CacheTTL < 0 {
+		return fmt.Errorf("inventory cache_ttl must not be negative")
+	}
+
+	if cfg.Inventory.ScriptTimeout <= 0 {
+		return fmt.Errorf("inventory script_timeout must be greater than 0")
+	}
+	if err := validateSheetColumns(cfg.Inventory.Columns); err != nil {
+		return err
+	}
+
+	if cfg.Facts.CacheTTL < 0 {
+		return fmt.Errorf("facts cache_ttl must not be negative")
 	}
+
 	if cfg.Execution.MaxWorkers <= 0 {
 		return fmt.Errorf("max_workers must be greater than 0")
 	}
@@ -115,6 +287,18 @@ func ValidateConfig(cfg *GossConfig) error {
 		return fmt.Errorf("task_timeout must be greater than 0")
 	}
 
+	if cfg.Execution.Become.PromptTimeout <= 0 {
+		return fmt.Errorf("become prompt_timeout must be greater than 0")
+	}
+
+	if _, err := easyssh.CompilePatterns(cfg.Execution.Become.Prompts); err != nil {
+		return fmt.Errorf("become prompts: %s", err.Error())
+	}
+
+	if _, err := easyssh.CompilePatterns(cfg.Execution.Become.Failures); err != nil {
+		return fmt.Errorf("become failures: %s", err.Error())
+	}
+
 	switch cfg.FileTransfer.OverwritePolicy {
 	case Always, Never:
 		// 有效值，不做处理
@@ -130,5 +314,76 @@ func ValidateConfig(cfg *GossConfig) error {
 		return fmt.Errorf("retries must be greater than 0")
 	}
 
+	if err := validateJumpHosts(cfg); err != nil {
+		return err
+	}
+
+	if err := validateAlgorithms(cfg); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// validateJumpHosts 校验跳板机配置并检测循环引用
+func validateJumpHosts(cfg *GossConfig) error {
+	for name, jump := range cfg.JumpHosts {
+		if jump == nil || jump.Address == "" {
+			return fmt.Errorf("jump host %s: address is required", name)
+		}
+		if jump.Port < 0 {
+			return fmt.Errorf("jump host %s: port must be greater than 0", name)
+		}
+		if _, err := cfg.JumpChain(name); err != nil {
+			return err
+		}
+	}
+	if cfg.Connection.DefaultJump != "" {
+		if _, ok := cfg.LookupJump(cfg.Connection.DefaultJump); !ok {
+			return fmt.Errorf("default_jump %s is not defined in jump_hosts", cfg.Connection.DefaultJump)
+		}
+	}
+	return nil
+}
+
+// NoJump 主机配置 jump=none 表示不使用 default_jump 直接连接
+const NoJump = "none"
+
+// HostJump 返回主机需要经过的最后一跳跳板机名称，直接连接时为空
+func (cfg *GossConfig) HostJump(host *Host) string {
+	name := host.Jump
+	if name == "" {
+		name = cfg.Connection.DefaultJump
+	}
+	if name == NoJump {
+		return ""
+	}
+	return name
+}
+
+// LookupJump 查找跳板机定义，名称以 ssh: 开头时按~/.ssh/config中的ProxyJump生成
+func (cfg *GossConfig) LookupJump(name string) (*JumpHost, bool) {
+	if jump, ok := cfg.JumpHosts[name]; ok {
+		return jump, true
+	}
+	return cfg.sshJumpHost(name)
+}
+
+// JumpChain 返回到达指定跳板机需要经过的完整链路，第一个元素为最外层跳板机
+func (cfg *GossConfig) JumpChain(name string) ([]string, error) {
+	var chain []string
+	seen := make(map[string]bool)
+	for current := name; current != ""; {
+		if seen[current] {
+			return nil, fmt.Errorf("jump host %s: circular jump chain detected", name)
+		}
+		seen[current] = true
+		jump, ok := cfg.LookupJump(current)
+		if !ok {
+			return nil, fmt.Errorf("jump host %s is not defined in jump_hosts", current)
+		}
+		chain = append([]string{current}, chain...)
+		current = jump.Jump
+	}
+	return chain, nil
+}
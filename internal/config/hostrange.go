@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 单个模式最多展开的主机数量，防止误写的大网段耗尽内存
+const maxExpandedHosts = 65536
+
+// 范围表达式，例如 [1:64]、[01:12]、[0:100:10]、[a:f]
+var hostRangePattern = regexp.MustCompile(`\[([0-9]+|[a-zA-Z]):([0-9]+|[a-zA-Z])(?::([0-9]+))?\]`)
+
+// expandHostPattern 展开主机地址中的范围与网段，返回的每一项仍可携带 :端口：
+//
+//	10.20.3.[1:64]      数字范围
+//	db[01:12].prod      补零的数字范围
+//	web[a:c]            字母范围
+//	10.0.0.[0:100:10]   带步长的范围
+//	10.0.1.0/28         CIDR网段，IPv4网段不包含网络地址与广播地址
+func expandHostPattern(field string) ([]string, error) {
+	if strings.Contains(field, "/") {
+		return expandCIDR(field)
+	}
+	// [IPv6]:端口 不是范围表达式
+	if strings.HasPrefix(field, "[") {
+		if end := strings.Index(field, "]"); end > 0 {
+			if _, err := netip.ParseAddr(field[1:end]); err == nil {
+				return []string{field}, nil
+			}
+		}
+	}
+	loc := hostRangePattern.FindStringSubmatchIndex(field)
+	if loc == nil {
+		return []string{field}, nil
+	}
+	items, err := expandRange(field[loc[2]:loc[3]], field[loc[4]:loc[5]], optionalGroup(field, loc, 6))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host range %q: %s", field, err.Error())
+	}
+	prefix, suffix := field[:loc[0]], field[loc[1]:]
+	// 后缀中可能还有范围，递归展开后做笛卡尔积
+	rests, err := expandHostPattern(suffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(items)*len(rests) > maxExpandedHosts {
+		return nil, fmt.Errorf("host range %q expands to more than %d hosts", field, maxExpandedHosts)
+	}
+	hosts := make([]string, 0, len(items)*len(rests))
+	for _, item := range items {
+		for _, rest := range rests {
+			hosts = append(hosts, prefix+item+rest)
+		}
+	}
+	return hosts, nil
+}
+
+func optionalGroup(s string, loc []int, i int) string {
+	if loc[i] < 0 {
+		return ""
+	}
+	return s[loc[i]:loc[i+1]]
+}
+
+// expandRange 展开单个范围，起始值以0开头时按起始值的位数补零
+func expandRange(start, end, step string) ([]string, error) {
+	inc := 1
+	if step != "" {
+		n, err := strconv.Atoi(step)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("step must be greater than 0")
+		}
+		inc = n
+	}
+	startNum, errStart := strconv.Atoi(start)
+	endNum, errEnd := strconv.Atoi(end)
+	var items []string
+	switch {
+	case errStart == nil && errEnd == nil:
+		if startNum > endNum {
+			return nil, fmt.Errorf("start %s is greater than end %s", start, end)
+		}
+		if (endNum-startNum)/inc+1 > maxExpandedHosts {
+			return nil, fmt.Errorf("range expands to more than %d hosts", maxExpandedHosts)
+		}
+		width := 0
+		if len(start) > 1 && start[0] == '0' {
+			width = len(start)
+		}
+		for i := startNum; i <= endNum; i += inc {
+			items = append(items, fmt.Sprintf("%0*d", width, i))
+		}
+	case errStart != nil && errEnd != nil:
+		// 字母范围要求大小写一致
+		s, e := start[0], end[0]
+		if (s >= 'a') != (e >= 'a') {
+			return nil, fmt.Errorf("letters %s and %s must have the same case", start, end)
+		}
+		if s > e {
+			return nil, fmt.Errorf("start %s is greater than end %s", start, end)
+		}
+		for c := int(s); c <= int(e); c += inc {
+			items = append(items, string(rune(c)))
+		}
+	default:
+		return nil, fmt.Errorf("cannot mix numbers and letters")
+	}
+	return items, nil
+}
+
+// expandCIDR 展开网段中的主机地址，IPv4网段(/31、/32除外)跳过网络地址与广播地址
+func expandCIDR(field string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(field)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", field)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("CIDR %q expands to more than %d hosts", field, maxExpandedHosts)
+	}
+	skipEdges := prefix.Addr().Is4() && hostBits >= 2
+	total := 1 << hostBits
+	hosts := make([]string, 0, total)
+	addr := prefix.Addr()
+	for i := 0; i < total; i, addr = i+1, addr.Next() {
+		if skipEdges && (i == 0 || i == total-1) {
+			continue
+		}
+		hosts = append(hosts, addr.String())
+	}
+	return hosts, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandHostPattern(t *testing.T) {
+	cases := []struct {
+		field   string
+		want    []string
+		wantLen int
+		wantErr string
+	}{
+		{field: "10.0.0.1", want: []string{"10.0.0.1"}},
+		{field: "web[1:3]", want: []string{"web1", "web2", "web3"}},
+		{field: "web[01:10]", want: []string{"web01", "web02", "web03", "web04", "web05", "web06", "web07", "web08", "web09", "web10"}},
+		{field: "db[001:002].prod", want: []string{"db001.prod", "db002.prod"}},
+		{field: "web[a:c]", want: []string{"weba", "webb", "webc"}},
+		{field: "rack[A:B]", want: []string{"rackA", "rackB"}},
+		{field: "10.0.0.[0:30:10]", want: []string{"10.0.0.0", "10.0.0.10", "10.0.0.20", "10.0.0.30"}},
+		{field: "10.0.0.[1:6:2]", want: []string{"10.0.0.1", "10.0.0.3", "10.0.0.5"}},
+		{field: "web[a:e:2]", want: []string{"weba", "webc", "webe"}},
+		{field: "r[1:2]n[a:b]", want: []string{"r1na", "r1nb", "r2na", "r2nb"}},
+		{field: "10.0.0.[1:2]:2222", want: []string{"10.0.0.1:2222", "10.0.0.2:2222"}},
+		{field: "[::1]:2222", want: []string{"[::1]:2222"}},
+		{field: "web[5:5]", want: []string{"web5"}},
+		{field: "web[3:1]", wantErr: "start 3 is greater than end 1"},
+		{field: "web[c:a]", wantErr: "start c is greater than end a"},
+		{field: "web[a:C]", wantErr: "same case"},
+		{field: "web[1:c]", wantErr: "cannot mix numbers and letters"},
+		{field: "web[1:3:0]", wantErr: "step must be greater than 0"},
+		{field: "10.[0:255].[0:255].[1:2]", wantErr: "expands to more than"},
+		{field: "10.0.0.1/32", want: []string{"10.0.0.1"}},
+		{field: "10.0.0.0/31", want: []string{"10.0.0.0", "10.0.0.1"}},
+		{field: "10.0.0.0/30", want: []string{"10.0.0.1", "10.0.0.2"}},
+		{field: "10.0.0.5/29", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}},
+		{field: "2001:db8::/126", want: []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}},
+		{field: "10.0.0.0/16", wantLen: 65534},
+		{field: "10.0.0.0/15", wantErr: "expands to more than"},
+		{field: "10.0.0.0/33", wantErr: "invalid CIDR"},
+		{field: "web/24", wantErr: "invalid CIDR"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.field, func(t *testing.T) {
+			hosts, err := expandHostPattern(tc.field)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantLen > 0 {
+				if len(hosts) != tc.wantLen {
+					t.Errorf("expected %d hosts, got %d", tc.wantLen, len(hosts))
+				}
+				return
+			}
+			if !slices.Equal(hosts, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, hosts)
+			}
+		})
+	}
+}
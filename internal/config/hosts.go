@@ -2,29 +2,120 @@ package config
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"goss/internal/facts"
+	"goss/internal/utils"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Host struct {
-	IP       string
+	// 主机清单中填写的原始名称(主机名、别名或IP)，用于结果展示与模板
+	Name string
+	// 用于ssh连接与主机密钥校验的主机名或IP，未通过address指定时可被~/.ssh/config的HostName替换
+	IP string
+	// 加载时解析得到的地址，不为空时实际连接该地址，主机密钥仍按IP校验
+	Addr     string
 	Port     string
 	User     string
 	Password string
 	SudoPass string
+	// 提权方式 su、sudo、doas 或 none，为空时使用su
+	BecomeMethod string
+	// 提权的目标用户，为空时为root
+	BecomeUser string
+	// 私钥文件路径，为空时使用全局配置 connection.identity_files
+	IdentityFile string
+	// 私钥保护密码
+	Passphrase string
+	// OpenSSH用户证书路径，为空时自动使用私钥旁的 -cert.pub 文件
+	CertificateFile string
+	// 跳板机名称，对应全局配置 jump_hosts 中的定义
+	Jump string
+	// 连接超时(秒)，0表示使用全局配置，可来自~/.ssh/config的ConnectTimeout
+	ConnectTimeout int
+	// 主机密钥校验模式，nil表示使用全局配置，可来自~/.ssh/config的StrictHostKeyChecking
+	SecurityMode *int
+	// 主机单独指定的算法策略，优先于全局配置
+	Algorithms AlgorithmPolicy
+	// 主机所属分组(含上级分组与all)
+	Groups []string
+	// 合并后的分组变量与主机变量，可在模板中通过 {{ .Vars.name }} 引用
+	Vars map[string]any
+	// 主机在清单文件中首次声明的行号，动态清单为0
+	Line int
+	// 采集到的主机信息，开启facts.gather或执行facts任务后可用
+	Facts *facts.Facts
 }
 
-func ParseHostsFile(path string) ([]*Host, error) {
-	file, err := os.Open(path)
+// DisplayName 返回用于展示的主机名称
+func (h *Host) DisplayName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.IP
+}
+
+// DialAddr 返回实际连接的地址
+func (h *Host) DialAddr() string {
+	if h.Addr != "" {
+		return h.Addr
+	}
+	return h.IP
+}
+
+// TemplateData 返回主机的模板变量
+func (h *Host) TemplateData() utils.TemplateData {
+	return utils.TemplateData{
+		IP:     h.DisplayName(),
+		Name:   h.DisplayName(),
+		Groups: h.Groups,
+		Vars:   h.Vars,
+		Facts:  h.Facts,
+	}
+}
+
+// Timeout 返回主机的连接超时(秒)
+func (h *Host) Timeout(conn *ConnectionConfig) int {
+	if h.ConnectTimeout > 0 {
+		return h.ConnectTimeout
+	}
+	return conn.ConnectTimeout
+}
+
+// Security 返回主机的主机密钥校验模式
+func (h *Host) Security(conn *ConnectionConfig) int {
+	if h.SecurityMode != nil {
+		return *h.SecurityMode
+	}
+	return conn.SecurityMode
+}
+
+// ParseHostsFile 加载主机清单，支持CSV、INI分组、YAML与动态清单，详见LoadInventory
+func ParseHostsFile(path string, opts *InventoryConfig) ([]*Host, error) {
+	inv, err := LoadInventory(path, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return inv.Hosts, nil
+}
 
-	var hosts []*Host
-	scanner := bufio.NewScanner(file)
+// parseCSVInventory 解析原有的 地址,用户名,登录密码,特权密码[,key=value...] 格式
+func parseCSVInventory(content []byte) (*Inventory, error) {
+	var (
+		hosts []*Host
+		errs  InventoryErrors
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -36,23 +127,315 @@ func ParseHostsFile(path string) ([]*Host, error) {
 			continue
 		}
 
-		// 分割字段
+		// 分割字段，只有地址时其余参数来自~/.ssh/config
 		parts := strings.Split(line, ",")
-		if len(parts) != 4 {
-			return nil, fmt.Errorf("invalid format at line %d", lineNum)
+		if strings.TrimSpace(parts[0]) == "" {
+			errs.add(lineNum, fmt.Errorf("invalid format"))
+			continue
+		}
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		// 范围与网段展开为多个主机，共用同一行的认证信息与参数
+		addresses, err := expandHostPattern(strings.TrimSpace(parts[0]))
+		if err != nil {
+			errs.add(lineNum, err)
+			continue
+		}
+		for _, addr := range addresses {
+			host, err := parseCSVHost(addr, parts, lineNum)
+			if err != nil {
+				errs.add(lineNum, err)
+				break
+			}
+			// 重复的主机在CompleteHosts填充端口后检查
+			hosts = append(hosts, host)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	inv := &Inventory{Hosts: hosts, Groups: map[string]*Group{
+		GroupAll:       {Name: GroupAll, Children: []string{GroupUngrouped}},
+		GroupUngrouped: {Name: GroupUngrouped},
+	}}
+	for _, host := range hosts {
+		inv.Groups[GroupUngrouped].Hosts = append(inv.Groups[GroupUngrouped].Hosts, host.Name)
+	}
+	return inv, nil
+}
+
+// parseCSVHost 根据展开后的地址与CSV行的其余列生成主机
+func parseCSVHost(addr string, parts []string, lineNum int) (*Host, error) {
+	// 未填写端口时留空，由CompleteHosts使用ssh配置或默认端口填充
+	name, port, err := parseHostAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	host := &Host{
+		Name:     name,
+		IP:       name,
+		Port:     port,
+		User:     strings.TrimSpace(parts[1]),
+		Password: strings.TrimSpace(parts[2]),
+		SudoPass: strings.TrimSpace(parts[3]),
+		Groups:   []string{GroupAll, GroupUngrouped},
+		Line:     lineNum,
+	}
+	if err := decryptValues(&host.User, &host.Password, &host.SudoPass); err != nil {
+		return nil, err
+	}
+	// 解析四列之后的可选 key=value 参数
+	if err := parseHostOptions(host, parts[4:]); err != nil {
+		return nil, err
+	}
+	return host, nil
+}
+
+// parseHostAddress 解析主机地址与可选端口，支持以下格式：
+// 10.0.0.1、10.0.0.1:2222、web01.example.com:2222、2001:db8::1、[2001:db8::1]:2222
+func parseHostAddress(field string) (string, string, error) {
+	host, port := field, ""
+	switch {
+	case strings.HasPrefix(field, "["):
+		end := strings.Index(field, "]")
+		if end < 0 {
+			return "", "", fmt.Errorf("invalid host address %q: missing ']'", field)
+		}
+		host = field[1:end]
+		rest := field[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return "", "", fmt.Errorf("invalid host address %q", field)
+			}
+			port = rest[1:]
+		}
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", "", fmt.Errorf("invalid IPv6 address %q", host)
+		}
+	case strings.Count(field, ":") == 1:
+		host, port, _ = strings.Cut(field, ":")
+	case strings.Count(field, ":") > 1:
+		// 多个冒号且没有方括号时整体视为IPv6地址
+		if _, err := netip.ParseAddr(field); err != nil {
+			return "", "", fmt.Errorf("invalid IPv6 address %q, use [address]:port to specify a port", field)
+		}
+	}
+	if host == "" || strings.ContainsAny(host, " \t/@") {
+		return "", "", fmt.Errorf("invalid host address %q", field)
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return "", "", fmt.Errorf("invalid port %q in host address %q", port, field)
+		}
+	}
+	return host, port, nil
+}
+
+// resolveHosts 并发解析非IP的主机名，结果写入Addr。
+// 经过跳板机的主机由跳板机解析，不在本地解析；解析失败的主机不影响其他主机，连接时报告失败
+func (cfg *GossConfig) resolveHosts(hosts []*Host) {
+	var wg sync.WaitGroup
+	timeout := time.Duration(cfg.Connection.ConnectTimeout) * time.Second
+	sem := make(chan struct{}, 32)
+	for _, host := range hosts {
+		if _, err := netip.ParseAddr(host.IP); err == nil {
+			continue
+		}
+		if cfg.HostJump(host) != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host *Host) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host.IP)
+			if err != nil {
+				slog.Warn("Failed to resolve host", "Host", host.DisplayName(), "Address", host.IP, "error", err)
+				return
+			}
+			if len(addrs) == 0 {
+				slog.Warn("Host resolved to no addresses", "Host", host.DisplayName(), "Address", host.IP)
+				return
+			}
+			host.Addr = addrs[0]
+		}(host)
+	}
+	wg.Wait()
+}
+
+// CompleteHosts 合并~/.ssh/config中匹配的Host块，主机清单中显式配置的值优先，
+// 仍未配置的端口使用default_port，用户名使用当前系统用户。密码引用由ResolveSecrets解析。
+// 名称、地址与端口都相同的主机只能出现一次，例如 10.0.0.1 与 10.0.0.1:22，避免任务重复执行
+func (cfg *GossConfig) CompleteHosts(hosts []*Host) error {
+	var errs InventoryErrors
+	declared := make(map[string]int)
+	for _, host := range hosts {
+		if err := cfg.completeHost(host); err != nil {
+			errs.add(host.Line, fmt.Errorf("host %s: %s", host.DisplayName(), err.Error()))
+			continue
+		}
+		key := host.DisplayName() + " " + net.JoinHostPort(host.IP, host.Port)
+		if first, ok := declared[key]; ok {
+			errs.add(host.Line, fmt.Errorf("duplicate host %s (first defined at line %d)", net.JoinHostPort(host.IP, host.Port), first))
+			continue
+		}
+		declared[key] = host.Line
+	}
+	if err := errs.err(); err != nil {
+		return err
+	}
+	if cfg.Connection.ResolveHosts {
+		cfg.resolveHosts(hosts)
+	}
+	return nil
+}
+
+func (cfg *GossConfig) completeHost(host *Host) error {
+	if err := cfg.applySSHConfig(host); err != nil {
+		return err
+	}
+	if host.Port == "" {
+		host.Port = strconv.Itoa(cfg.Connection.DefaultPort)
+	}
+	if host.User == "" {
+		host.User = localUser()
+	}
+	return host.Algorithms.validate()
+}
+
+// CheckHosts 检查主机是否可以发起连接：至少有一种认证方式(密码、私钥文件或ssh-agent)，
+// 私钥文件存在，跳板机已定义。返回的错误带有主机在清单中的行号
+func (cfg *GossConfig) CheckHosts(hosts []*Host) error {
+	var errs InventoryErrors
+	useAgent := cfg.Connection.UseAgent && easyssh.AgentSocketFromEnv() != ""
+	for _, host := range hosts {
+		identityFiles := cfg.Connection.IdentityFiles
+		if host.IdentityFile != "" {
+			identityFiles = []string{host.IdentityFile}
 		}
-		port := strconv.Itoa(DefaultPort)
-		if strings.Contains(parts[0], ":") {
-			port = strings.Split(parts[0], ":")[1]
+		if host.Password == "" && len(identityFiles) == 0 && !useAgent {
+			errs.add(host.Line, fmt.Errorf("host %s: no credentials, set a password or identity_file, or enable connection.use_agent with a running ssh-agent", host.DisplayName()))
 		}
-		host := Host{
-			IP:       strings.TrimSpace(parts[0]),
-			Port:     port,
-			User:     strings.TrimSpace(parts[1]),
-			Password: strings.TrimSpace(parts[2]),
-			SudoPass: strings.TrimSpace(parts[3]),
+		if host.IdentityFile != "" {
+			if _, err := os.Stat(easyssh.ExpandHome(host.IdentityFile)); err != nil {
+				errs.add(host.Line, fmt.Errorf("host %s: identity file %s is not readable: %s", host.DisplayName(), host.IdentityFile, err.Error()))
+			}
+		}
+		if host.Jump != "" {
+			if _, err := cfg.JumpChain(host.Jump); err != nil {
+				errs.add(host.Line, fmt.Errorf("host %s: %s", host.DisplayName(), err.Error()))
+			}
+		}
+	}
+	return errs.err()
+}
+
+// parseHostOptions 解析主机行的扩展参数，例如 identity_file=~/.ssh/id_ed25519
+func parseHostOptions(host *Host, options []string) error {
+	for _, opt := range options {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok {
+			return fmt.Errorf("invalid host option %q, expected key=value", opt)
+		}
+		value, err := decryptValue(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		known, err := setHostOption(host, strings.TrimSpace(key), value)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return fmt.Errorf("unknown host option %q", key)
+		}
+	}
+	return nil
+}
+
+// setHostOption 设置主机的连接参数，主机行扩展参数与清单变量共用，返回false表示不是连接参数
+func setHostOption(host *Host, key, value string) (bool, error) {
+	switch key {
+	case "address":
+		name, port, err := parseHostAddress(value)
+		if err != nil {
+			return true, err
+		}
+		host.IP = name
+		if port != "" {
+			host.Port = port
+		}
+	case "port":
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 || n > 65535 {
+			return true, fmt.Errorf("invalid port %q", value)
+		}
+		host.Port = value
+	case "user":
+		host.User = value
+	case "password":
+		host.Password = value
+	case "sudo_pass":
+		host.SudoPass = value
+	case "become_method":
+		if !easyssh.ValidBecomeMethod(value) {
+			return true, fmt.Errorf("invalid become_method %q, must be one of su, sudo, doas, none", value)
+		}
+		host.BecomeMethod = value
+	case "become_user":
+		host.BecomeUser = value
+	case "identity_file":
+		host.IdentityFile = value
+	case "passphrase":
+		host.Passphrase = value
+	case "certificate_file":
+		host.CertificateFile = value
+	case "jump":
+		host.Jump = value
+	case "connect_timeout":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return true, fmt.Errorf("invalid connect_timeout %q", value)
+		}
+		host.ConnectTimeout = n
+	case "security_mode":
+		mode, err := strconv.Atoi(value)
+		if err != nil || mode < 0 || mode > 3 {
+			return true, fmt.Errorf("invalid security_mode %q, must be 0-3", value)
+		}
+		host.SecurityMode = &mode
+	// 算法列表使用 : 分隔，例如 ciphers=aes128-ctr:aes256-ctr
+	case "ciphers":
+		host.Algorithms.Ciphers = splitAlgorithms(value)
+	case "key_exchanges":
+		host.Algorithms.KeyExchanges = splitAlgorithms(value)
+	case "macs":
+		host.Algorithms.MACs = splitAlgorithms(value)
+	case "host_key_algorithms":
+		host.Algorithms.HostKeyAlgorithms = splitAlgorithms(value)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func splitAlgorithms(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ":") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
 		}
-		hosts = append(hosts, &host)
 	}
-	return hosts, scanner.Err()
+	return names
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHostAddress(t *testing.T) {
+	cases := []struct {
+		field    string
+		wantHost string
+		wantPort string
+		wantErr  string
+	}{
+		{field: "10.0.0.1", wantHost: "10.0.0.1"},
+		{field: "10.0.0.1:2222", wantHost: "10.0.0.1", wantPort: "2222"},
+		{field: "web01.example.com", wantHost: "web01.example.com"},
+		{field: "web01.example.com:22", wantHost: "web01.example.com", wantPort: "22"},
+		{field: "::1", wantHost: "::1"},
+		{field: "2001:db8::1", wantHost: "2001:db8::1"},
+		{field: "[::1]:2222", wantHost: "::1", wantPort: "2222"},
+		{field: "[2001:db8::1]", wantHost: "2001:db8::1"},
+		{field: "[::1", wantErr: "missing ']'"},
+		{field: "[::1]2222", wantErr: "invalid host address"},
+		{field: "[web01]:22", wantErr: "invalid IPv6 address"},
+		{field: "2001:db8::zz", wantErr: "use [address]:port"},
+		{field: "web01:ssh", wantErr: "invalid port"},
+		{field: "web01:0", wantErr: "invalid port"},
+		{field: "web01:65536", wantErr: "invalid port"},
+		{field: ":22", wantErr: "invalid host address"},
+		{field: "deploy@web01", wantErr: "invalid host address"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.field, func(t *testing.T) {
+			host, port, err := parseHostAddress(tc.field)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tc.wantHost || port != tc.wantPort {
+				t.Errorf("expected %q %q, got %q %q", tc.wantHost, tc.wantPort, host, port)
+			}
+		})
+	}
+}
+
+func TestCSVDuplicateHosts(t *testing.T) {
+	cfg := &GossConfig{Connection: &ConnectionConfig{DefaultPort: 22}}
+	cases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "different ports", content: "10.0.0.1,root,pass,\n10.0.0.1:2222,root,pass,\n"},
+		{name: "same address", content: "10.0.0.1,root,pass,\n10.0.0.1,root,pass,\n", wantErr: "duplicate host 10.0.0.1:22 (first defined at line 1) at line 2"},
+		{name: "explicit default port", content: "10.0.0.1,root,pass,\n10.0.0.1:22,root,pass,\n", wantErr: "duplicate host 10.0.0.1:22 (first defined at line 1) at line 2"},
+		{name: "range overlaps a host", content: "10.0.0.[1:3],root,pass,\n10.0.0.2:22,root,pass,\n", wantErr: "duplicate host 10.0.0.2:22 (first defined at line 1) at line 2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inv, err := parseCSVInventory([]byte(tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.CompleteHosts(inv.Hosts)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,657 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// GroupAll 包含清单中全部主机的隐式分组
+	GroupAll = "all"
+	// GroupUngrouped 未加入任何分组的主机
+	GroupUngrouped = "ungrouped"
+)
+
+// 不写入模板变量的敏感参数
+var secretHostVars = []string{"password", "sudo_pass", "passphrase"}
+
+// INI格式的分组声明，例如 [web]、[web:vars]、[prod:children]
+var iniSectionPattern = regexp.MustCompile(`^\[([A-Za-z0-9_.-]+)(?::(vars|children))?\]$`)
+
+// Group 主机分组，Hosts为直接属于该分组的主机名称
+type Group struct {
+	Name     string
+	Hosts    []string
+	Children []string
+	Vars     map[string]any
+}
+
+// Inventory 主机清单，Hosts保持清单中首次声明的顺序
+type Inventory struct {
+	Hosts  []*Host
+	Groups map[string]*Group
+}
+
+// InventoryError 主机清单中的单个错误，Line为0表示没有行号(例如动态清单)
+type InventoryError struct {
+	Line int
+	Msg  string
+}
+
+func (e *InventoryError) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s at line %d", e.Msg, e.Line)
+}
+
+// InventoryErrors 加载主机清单时收集到的全部错误，解析不会在第一个错误处停止
+type InventoryErrors []*InventoryError
+
+func (e InventoryErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *InventoryErrors) add(line int, err error) {
+	*e = append(*e, &InventoryError{Line: line, Msg: err.Error()})
+}
+
+// err 按行号排序后返回，没有错误时返回nil
+func (e InventoryErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Line < e[j].Line })
+	return e
+}
+
+// LoadInventory 加载主机清单，根据文件属性、扩展名与内容识别格式：
+// 可执行程序为动态清单；.xlsx 与带表头的 .csv 为表格清单；.json 为动态清单输出格式(例如 goss inventory export 的结果)；.yaml/.yml 为YAML分组格式；包含 [group] 段的文件为INI分组格式；其他为原有的CSV格式
+func LoadInventory(path string, opts *InventoryConfig) (*Inventory, error) {
+	if isInventoryScript(path) {
+		return loadScriptInventory(path, opts)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// 整体加密的清单先解密，单个加密值在设置主机参数时解密
+	if content, err = decryptContent(content); err != nil {
+		return nil, fmt.Errorf("inventory %s: %w", path, err)
+	}
+	switch {
+	case isSheetFile(path, content, opts):
+		return loadSheetInventory(path, content, opts)
+	case strings.EqualFold(filepath.Ext(path), ".json"):
+		return parseJSONInventory(content)
+	case isYAMLFile(path):
+		return parseYAMLInventory(content)
+	case isINIInventory(content):
+		return parseINIInventory(content)
+	default:
+		return parseCSVInventory(content)
+	}
+}
+
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// isINIInventory 判断是否包含分组声明，IPv6地址行 [::1]:22 不会被识别为分组
+func isINIInventory(content []byte) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		if iniSectionPattern.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseINIInventory 解析INI分组格式：
+//
+//	10.0.0.9 user=root          # 第一个分组之前的主机为未分组主机
+//	[web]
+//	web01 address=10.0.0.1 port=2222
+//	[web:vars]
+//	user=deploy
+//	[prod:children]
+//	web
+func parseINIInventory(content []byte) (*Inventory, error) {
+	b := newInventoryBuilder()
+	group, kind := "", ""
+	for i, raw := range strings.Split(string(content), "\n") {
+		lineNum := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if m := iniSectionPattern.FindStringSubmatch(line); m != nil {
+			group, kind = m[1], m[2]
+			b.group(group)
+			continue
+		}
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				b.errs.add(lineNum, fmt.Errorf("invalid group variable %q, expected key=value", line))
+				continue
+			}
+			value, err := unquoteINIValue(strings.TrimSpace(value))
+			if err != nil {
+				b.errs.add(lineNum, err)
+				continue
+			}
+			b.group(group).Vars[key] = value
+		case "children":
+			if err := b.addChild(group, line); err != nil {
+				b.errs.add(lineNum, err)
+			}
+		default:
+			fields, err := splitINIFields(line)
+			if err == nil && len(fields) == 0 {
+				err = fmt.Errorf("missing host name")
+			}
+			if err != nil {
+				b.errs.add(lineNum, err)
+				continue
+			}
+			vars := make(map[string]any)
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok || key == "" {
+					err = fmt.Errorf("invalid host variable %q, expected key=value", field)
+					break
+				}
+				vars[key] = value
+			}
+			if err == nil {
+				err = b.addHost(group, fields[0], vars, lineNum)
+			}
+			if err != nil {
+				b.errs.add(lineNum, err)
+			}
+		}
+	}
+	return b.build()
+}
+
+// splitINIFields 按空白拆分主机行，值可以使用单引号或双引号包含空格
+func splitINIFields(line string) ([]string, error) {
+	var (
+		fields []string
+		cur    strings.Builder
+		quote  rune
+	)
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		case quote == 0 && r == '#':
+			// 行尾注释
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+			}
+			return fields, nil
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+func unquoteINIValue(value string) (string, error) {
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if len(value) < 2 || value[len(value)-1] != value[0] {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		return value[1 : len(value)-1], nil
+	}
+	return value, nil
+}
+
+// parseYAMLInventory 解析YAML分组格式，顶层为分组，分组下可包含 hosts、vars、children：
+//
+//	all:
+//	  vars:
+//	    user: deploy
+//	  children:
+//	    web:
+//	      hosts:
+//	        web01:
+//	          address: 10.0.0.1
+//	        10.0.0.2:2222:
+func parseYAMLInventory(content []byte) (*Inventory, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid yaml inventory: %s", err.Error())
+	}
+	b := newInventoryBuilder()
+	if len(doc.Content) == 0 {
+		return b.build()
+	}
+	root := doc.Content[0]
+	if isYAMLNull(root) {
+		return b.build()
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, InventoryErrors{{Line: root.Line, Msg: "inventory must be a mapping of groups"}}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		b.parseYAMLGroup(root.Content[i].Value, "", root.Content[i+1])
+	}
+	return b.build()
+}
+
+// parseYAMLGroup 解析分组及其子分组，错误记录到builder后继续解析其余部分
+func (b *inventoryBuilder) parseYAMLGroup(name, parent string, node *yaml.Node) {
+	if !validGroupName(name) {
+		b.errs.add(node.Line, fmt.Errorf("invalid group name %q", name))
+		return
+	}
+	b.group(name)
+	if parent != "" {
+		if err := b.addChild(parent, name); err != nil {
+			b.errs.add(node.Line, err)
+		}
+	}
+	if isYAMLNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		b.errs.add(node.Line, fmt.Errorf("group %s must be a mapping", name))
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "hosts":
+			if isYAMLNull(value) {
+				continue
+			}
+			if value.Kind != yaml.MappingNode {
+				b.errs.add(value.Line, fmt.Errorf("hosts of group %s must be a mapping", name))
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				hostNode, varsNode := value.Content[j], value.Content[j+1]
+				vars, err := decodeYAMLVars(varsNode)
+				if err == nil {
+					err = b.addHost(name, hostNode.Value, vars, hostNode.Line)
+				}
+				if err != nil {
+					b.errs.add(hostNode.Line, err)
+				}
+			}
+		case "vars":
+			vars, err := decodeYAMLVars(value)
+			if err != nil {
+				b.errs.add(value.Line, err)
+				continue
+			}
+			for k, v := range vars {
+				b.group(name).Vars[k] = v
+			}
+		case "children":
+			if isYAMLNull(value) {
+				continue
+			}
+			if value.Kind != yaml.MappingNode {
+				b.errs.add(value.Line, fmt.Errorf("children of group %s must be a mapping", name))
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				b.parseYAMLGroup(value.Content[j].Value, name, value.Content[j+1])
+			}
+		default:
+			b.errs.add(key.Line, fmt.Errorf("unknown key %q in group %s", key.Value, name))
+		}
+	}
+}
+
+func decodeYAMLVars(node *yaml.Node) (map[string]any, error) {
+	vars := make(map[string]any)
+	if isYAMLNull(node) {
+		return vars, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("variables must be a mapping")
+	}
+	if err := node.Decode(&vars); err != nil {
+		return nil, fmt.Errorf("invalid variables: %s", err.Error())
+	}
+	return vars, nil
+}
+
+func isYAMLNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func validGroupName(name string) bool {
+	return iniSectionPattern.MatchString("["+name+"]") && !strings.Contains(name, ":")
+}
+
+// inventoryHost 构建过程中的主机，变量在build时与分组变量合并
+type inventoryHost struct {
+	name string
+	vars map[string]any
+	line int
+}
+
+type inventoryBuilder struct {
+	groups map[string]*Group
+	hosts  map[string]*inventoryHost
+	order  []string
+	// 分组与主机名称到首次声明行号，用于检测展开后的重复主机
+	declared map[string]int
+	errs     InventoryErrors
+}
+
+func newInventoryBuilder() *inventoryBuilder {
+	return &inventoryBuilder{
+		groups:   make(map[string]*Group),
+		hosts:    make(map[string]*inventoryHost),
+		declared: make(map[string]int),
+	}
+}
+
+func (b *inventoryBuilder) group(name string) *Group {
+	g, ok := b.groups[name]
+	if !ok {
+		g = &Group{Name: name, Vars: make(map[string]any)}
+		b.groups[name] = g
+	}
+	return g
+}
+
+func (b *inventoryBuilder) addChild(parent, child string) error {
+	if !validGroupName(child) {
+		return fmt.Errorf("invalid group name %q", child)
+	}
+	if child == GroupAll {
+		return fmt.Errorf("group %s cannot be a child group", GroupAll)
+	}
+	g := b.group(parent)
+	b.group(child)
+	if !slices.Contains(g.Children, child) {
+		g.Children = append(g.Children, child)
+	}
+	return nil
+}
+
+// addHost 声明主机，地址中的范围与网段展开为多个主机；同一主机在不同分组中多次声明时合并变量，
+// 后声明的值优先，在同一分组中重复声明视为错误
+func (b *inventoryBuilder) addHost(group, address string, vars map[string]any, line int) error {
+	addresses, err := expandHostPattern(strings.TrimSpace(address))
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		name, port, err := parseHostAddress(addr)
+		if err != nil {
+			return err
+		}
+		key := group + "\x00" + name
+		if first, ok := b.declared[key]; ok {
+			return duplicateHostError(name, group, first)
+		}
+		b.declared[key] = line
+		h, ok := b.hosts[name]
+		if !ok {
+			h = &inventoryHost{name: name, vars: make(map[string]any), line: line}
+			b.hosts[name] = h
+			b.order = append(b.order, name)
+		}
+		// 名称中的端口等同于主机变量port
+		if _, ok := vars["port"]; !ok && port != "" {
+			h.vars["port"] = port
+		}
+		for k, v := range vars {
+			h.vars[k] = v
+		}
+		if group != "" {
+			g := b.group(group)
+			g.Hosts = append(g.Hosts, name)
+		}
+	}
+	return nil
+}
+
+func duplicateHostError(name, group string, firstLine int) error {
+	if group == "" {
+		group = GroupUngrouped
+	}
+	if firstLine == 0 {
+		return fmt.Errorf("duplicate host %s in group %s", name, group)
+	}
+	return fmt.Errorf("duplicate host %s in group %s (first defined at line %d)", name, group, firstLine)
+}
+
+// build 补全all与ungrouped分组，检查分组循环引用，并按分组层级合并变量：
+// 层级越深的分组优先，同一层级按名称排序，主机变量最优先
+func (b *inventoryBuilder) build() (*Inventory, error) {
+	all := b.group(GroupAll)
+	parents := make(map[string][]string)
+	for _, g := range b.groups {
+		for _, child := range g.Children {
+			parents[child] = append(parents[child], g.Name)
+		}
+	}
+	if err := b.checkCycles(); err != nil {
+		b.errs.add(0, err)
+		return nil, b.errs.err()
+	}
+	// 没有上级的分组归入all
+	names := make([]string, 0, len(b.groups))
+	for name := range b.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != GroupAll && name != GroupUngrouped && len(parents[name]) == 0 {
+			all.Children = append(all.Children, name)
+			parents[name] = []string{GroupAll}
+		}
+	}
+
+	// 只属于all的主机归入ungrouped
+	direct := make(map[string][]string)
+	for _, name := range names {
+		for _, host := range b.groups[name].Hosts {
+			direct[host] = append(direct[host], name)
+		}
+	}
+	ungrouped := b.group(GroupUngrouped)
+	for _, host := range b.order {
+		groups := direct[host]
+		if len(groups) == 0 || (len(groups) == 1 && groups[0] == GroupAll) {
+			if !slices.Contains(ungrouped.Hosts, host) {
+				ungrouped.Hosts = append(ungrouped.Hosts, host)
+				direct[host] = append(direct[host], GroupUngrouped)
+			}
+		}
+	}
+	if !slices.Contains(all.Children, GroupUngrouped) {
+		all.Children = append(all.Children, GroupUngrouped)
+	}
+	parents[GroupUngrouped] = []string{GroupAll}
+
+	depths := make(map[string]int)
+	var depth func(name string) int
+	depth = func(name string) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		d := 0
+		for _, p := range parents[name] {
+			d = max(d, depth(p)+1)
+		}
+		depths[name] = d
+		return d
+	}
+
+	inv := &Inventory{Groups: b.groups}
+	for _, name := range b.order {
+		ih := b.hosts[name]
+		// 收集直接分组及其全部上级分组
+		seen := map[string]bool{GroupAll: true}
+		queue := slices.Clone(direct[name])
+		for len(queue) > 0 {
+			g := queue[0]
+			queue = queue[1:]
+			if seen[g] {
+				continue
+			}
+			seen[g] = true
+			queue = append(queue, parents[g]...)
+		}
+		groups := make([]string, 0, len(seen))
+		for g := range seen {
+			groups = append(groups, g)
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if di, dj := depth(groups[i]), depth(groups[j]); di != dj {
+				return di < dj
+			}
+			return groups[i] < groups[j]
+		})
+		vars := make(map[string]any)
+		for _, g := range groups {
+			for k, v := range b.groups[g].Vars {
+				vars[k] = v
+			}
+		}
+		for k, v := range ih.vars {
+			vars[k] = v
+		}
+		host, err := newInventoryHost(name, groups, vars)
+		if err != nil {
+			b.errs.add(ih.line, fmt.Errorf("host %s: %s", name, err.Error()))
+			continue
+		}
+		host.Line = ih.line
+		inv.Hosts = append(inv.Hosts, host)
+	}
+	if err := b.errs.err(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// checkCycles 检查children中的循环引用
+func (b *inventoryBuilder) checkCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("group cycle detected: %s", strings.Join(append(path, name), " -> "))
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		if g, ok := b.groups[name]; ok {
+			for _, child := range g.Children {
+				if err := visit(child, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	names := make([]string, 0, len(b.groups))
+	for name := range b.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newInventoryHost 根据合并后的变量生成主机，连接参数写入对应字段，其余作为自定义变量
+func newInventoryHost(name string, groups []string, vars map[string]any) (*Host, error) {
+	host := &Host{Name: name, IP: name, Groups: groups, Vars: make(map[string]any)}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	// address可能携带端口，需要先于port处理
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == "address") != (keys[j] == "address") {
+			return keys[i] == "address"
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		value := vars[key]
+		if s, ok := value.(string); ok {
+			plain, err := decryptValue(s)
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: %w", key, err)
+			}
+			value = plain
+		}
+		if _, err := setHostOption(host, key, varString(value)); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(secretHostVars, key) {
+			host.Vars[key] = value
+		}
+	}
+	return host, nil
+}
+
+// varString 将变量值转换为连接参数，列表按 : 拼接(用于算法列表)
+func varString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case int:
+		return strconv.Itoa(val)
+	case []any:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			items = append(items, varString(item))
+		}
+		return strings.Join(items, ":")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 主机清单导出格式
+const (
+	ExportINI  = "ini"
+	ExportJSON = "json"
+	ExportYAML = "yaml"
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// 表格导出的列，与导入时的默认列名一致
+var inventoryColumns = []string{"name", "address", "port", "user", "password", "sudo_pass", "groups", "identity_file", "jump"}
+
+// 自定义变量中名称包含这些关键字的值视为敏感信息
+var secretVarPattern = regexp.MustCompile(`(?i)pass|secret|token|private`)
+
+// SecretMask 展示时替代敏感信息的字符串
+const SecretMask = "******"
+
+// MaskSecret 非空的敏感信息替换为掩码
+func MaskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+// IsSecretVar 判断变量名是否表示敏感信息
+func IsSecretVar(name string) bool {
+	return slices.Contains(secretHostVars, name) || secretVarPattern.MatchString(name)
+}
+
+// DirectGroups 返回直接包含该主机的分组，不含all与ungrouped
+func (inv *Inventory) DirectGroups(host string) []string {
+	var groups []string
+	for name, g := range inv.Groups {
+		if name != GroupAll && name != GroupUngrouped && slices.Contains(g.Hosts, host) {
+			groups = append(groups, name)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// SortedGroups 返回按名称排序的分组名称
+func (inv *Inventory) SortedGroups() []string {
+	names := make([]string, 0, len(inv.Groups))
+	for name := range inv.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HostVars 返回主机解析后的全部变量，includeSecrets为false时不包含密码类变量
+func (h *Host) HostVars(includeSecrets bool) map[string]any {
+	vars := make(map[string]any, len(h.Vars)+8)
+	for k, v := range h.Vars {
+		if includeSecrets || !IsSecretVar(k) {
+			vars[k] = v
+		}
+	}
+	set := func(key, value string) {
+		if value != "" {
+			vars[key] = value
+		} else {
+			delete(vars, key)
+		}
+	}
+	if h.IP != h.Name {
+		vars["address"] = h.IP
+	}
+	if port, err := strconv.Atoi(h.Port); err == nil {
+		vars["port"] = port
+	}
+	set("user", h.User)
+	set("identity_file", h.IdentityFile)
+	set("certificate_file", h.CertificateFile)
+	set("jump", h.Jump)
+	set("become_method", h.BecomeMethod)
+	set("become_user", h.BecomeUser)
+	if h.ConnectTimeout > 0 {
+		vars["connect_timeout"] = h.ConnectTimeout
+	}
+	if h.SecurityMode != nil {
+		vars["security_mode"] = *h.SecurityMode
+	}
+	for key, list := range map[string][]string{
+		"ciphers":             h.Algorithms.Ciphers,
+		"key_exchanges":       h.Algorithms.KeyExchanges,
+		"macs":                h.Algorithms.MACs,
+		"host_key_algorithms": h.Algorithms.HostKeyAlgorithms,
+	} {
+		if len(list) > 0 {
+			vars[key] = list
+		}
+	}
+	if includeSecrets {
+		set("password", h.Password)
+		set("sudo_pass", h.SudoPass)
+		set("passphrase", h.Passphrase)
+	}
+	return vars
+}
+
+// Export 将主机清单导出为 ini、yaml(原生分组格式)、json(与动态清单格式相同)、csv 或 xlsx
+func (inv *Inventory) Export(w io.Writer, format string, includeSecrets bool) error {
+	switch format {
+	case ExportINI:
+		return inv.exportINI(w, includeSecrets)
+	case ExportJSON:
+		return inv.exportJSON(w, includeSecrets)
+	case ExportYAML:
+		return inv.exportYAML(w, includeSecrets)
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		cw.WriteAll(inv.exportRows(includeSecrets))
+		return cw.Error()
+	case ExportXLSX:
+		return inv.exportXLSX(w, includeSecrets)
+	}
+	return fmt.Errorf("unsupported export format %q, expected one of: ini, yaml, json, csv, xlsx", format)
+}
+
+func (inv *Inventory) exportJSON(w io.Writer, includeSecrets bool) error {
+	out := make(map[string]any, len(inv.Groups)+1)
+	hostVars := make(map[string]any, len(inv.Hosts))
+	for _, h := range inv.Hosts {
+		hostVars[h.Name] = h.HostVars(includeSecrets)
+	}
+	out[inventoryMetaKey] = map[string]any{"hostvars": hostVars}
+	for name, g := range inv.Groups {
+		out[name] = jsonGroup{Hosts: g.Hosts, Vars: exportGroupVars(g, includeSecrets), Children: g.Children}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func exportGroupVars(g *Group, includeSecrets bool) map[string]any {
+	vars := make(map[string]any, len(g.Vars))
+	for k, v := range g.Vars {
+		if includeSecrets || !IsSecretVar(k) {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// exportYAML 按分组层级输出，被多个上级分组包含的分组只在第一次出现时写出主机与变量
+func (inv *Inventory) exportYAML(w io.Writer, includeSecrets bool) error {
+	hosts := make(map[string]*Host, len(inv.Hosts))
+	for _, h := range inv.Hosts {
+		hosts[h.Name] = h
+	}
+	written := make(map[string]bool)
+	hostWritten := make(map[string]bool)
+	var groupNode func(name string) *yaml.Node
+	groupNode = func(name string) *yaml.Node {
+		g := inv.Groups[name]
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if g == nil || written[name] {
+			return node
+		}
+		written[name] = true
+		if vars := exportGroupVars(g, includeSecrets); len(vars) > 0 {
+			v := &yaml.Node{}
+			if err := v.Encode(vars); err == nil {
+				node.Content = append(node.Content, yamlScalar("vars"), v)
+			}
+		}
+		if len(g.Hosts) > 0 {
+			hostsNode := &yaml.Node{Kind: yaml.MappingNode}
+			for _, name := range g.Hosts {
+				v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+				if h := hosts[name]; h != nil && !hostWritten[name] {
+					hostWritten[name] = true
+					if err := v.Encode(h.HostVars(includeSecrets)); err != nil {
+						return nil
+					}
+				}
+				hostsNode.Content = append(hostsNode.Content, yamlScalar(name), v)
+			}
+			node.Content = append(node.Content, yamlScalar("hosts"), hostsNode)
+		}
+		if len(g.Children) > 0 {
+			children := &yaml.Node{Kind: yaml.MappingNode}
+			for _, child := range g.Children {
+				if child == GroupUngrouped && len(inv.Groups[child].Hosts) == 0 {
+					continue
+				}
+				children.Content = append(children.Content, yamlScalar(child), groupNode(child))
+			}
+			node.Content = append(node.Content, yamlScalar("children"), children)
+		}
+		return node
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, yamlScalar(GroupAll), groupNode(GroupAll))
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// exportINI 输出INI分组格式，主机变量只写在主机第一次出现的位置
+func (inv *Inventory) exportINI(w io.Writer, includeSecrets bool) error {
+	hosts := make(map[string]*Host, len(inv.Hosts))
+	for _, h := range inv.Hosts {
+		hosts[h.Name] = h
+	}
+	var buf strings.Builder
+	written := make(map[string]bool)
+	writeHosts := func(names []string) {
+		for _, name := range names {
+			buf.WriteString(name)
+			if h := hosts[name]; h != nil && !written[name] {
+				written[name] = true
+				buf.WriteString(iniVars(h.HostVars(includeSecrets), " "))
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	// 未分组的主机写在第一个分组之前
+	if g := inv.Groups[GroupUngrouped]; g != nil {
+		writeHosts(g.Hosts)
+	}
+	for _, name := range inv.SortedGroups() {
+		g := inv.Groups[name]
+		if name == GroupUngrouped {
+			continue
+		}
+		if name != GroupAll && len(g.Hosts) > 0 {
+			fmt.Fprintf(&buf, "\n[%s]\n", name)
+			writeHosts(g.Hosts)
+		}
+		if vars := exportGroupVars(g, includeSecrets); len(vars) > 0 {
+			fmt.Fprintf(&buf, "\n[%s:vars]\n", name)
+			buf.WriteString(strings.TrimPrefix(iniVars(vars, "\n"), "\n"))
+			buf.WriteByte('\n')
+		}
+		var children []string
+		for _, child := range g.Children {
+			if child != GroupUngrouped {
+				children = append(children, child)
+			}
+		}
+		if name != GroupAll && len(children) > 0 {
+			fmt.Fprintf(&buf, "\n[%s:children]\n%s\n", name, strings.Join(children, "\n"))
+		}
+	}
+	_, err := io.WriteString(w, strings.TrimPrefix(buf.String(), "\n"))
+	return err
+}
+
+// iniVars 按变量名排序输出 key=value，每项前加sep，包含空白或引号的值加引号
+func iniVars(vars map[string]any, sep string) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for _, k := range keys {
+		value := varString(vars[k])
+		if list, ok := vars[k].([]string); ok {
+			value = strings.Join(list, ":")
+		}
+		if strings.ContainsAny(value, " \t#'\"") || value == "" {
+			quote := `"`
+			if strings.Contains(value, `"`) {
+				quote = "'"
+			}
+			value = quote + value + quote
+		}
+		buf.WriteString(sep + k + "=" + value)
+	}
+	return buf.String()
+}
+
+func yamlScalar(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: s}
+}
+
+// exportRows 表格格式的表头与每个主机一行
+func (inv *Inventory) exportRows(includeSecrets bool) [][]string {
+	rows := [][]string{inventoryColumns}
+	for _, h := range inv.Hosts {
+		password, sudoPass := h.Password, h.SudoPass
+		if !includeSecrets {
+			password, sudoPass = "", ""
+		}
+		address := ""
+		if h.IP != h.Name {
+			address = h.IP
+		}
+		rows = append(rows, []string{
+			h.Name, address, h.Port, h.User, password, sudoPass,
+			strings.Join(inv.DirectGroups(h.Name), ";"), h.IdentityFile, h.Jump,
+		})
+	}
+	return rows
+}
+
+func (inv *Inventory) exportXLSX(w io.Writer, includeSecrets bool) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Sheet1"
+	for r, row := range inv.exportRows(includeSecrets) {
+		for c, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(c+1, r+1)
+			f.SetCellStr(sheet, cell, value)
+		}
+	}
+	return f.Write(w)
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"goss/internal/xerrors"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 动态清单JSON中保存主机变量的键
+const inventoryMetaKey = "_meta"
+
+// InventoryConfig 主机清单相关配置
+type InventoryConfig struct {
+	// 动态清单输出的缓存时间(秒)，0表示不缓存
+	CacheTTL int `mapstructure:"cache_ttl"`
+	// 动态清单缓存目录
+	CacheDir string `mapstructure:"cache_dir"`
+	// 执行动态清单程序的超时时间(秒)
+	ScriptTimeout int `mapstructure:"script_timeout"`
+	// 表格清单(.xlsx)读取的工作表，为空时使用第一个工作表
+	Sheet string `mapstructure:"sheet"`
+	// 表格清单的列映射，键为 name/address/port/user/password/sudo_pass/groups/identity_file/jump，值为表头
+	Columns map[string]string `mapstructure:"columns"`
+}
+
+// isInventoryScript 判断 --hosts 是否指向可执行的动态清单程序(带#!的脚本或ELF程序)
+func isInventoryScript(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	n, _ := f.Read(head)
+	return bytes.HasPrefix(head[:n], []byte("#!")) || bytes.Equal(head[:n], []byte("\x7fELF"))
+}
+
+// loadScriptInventory 执行动态清单程序(参数 --list)并解析标准输出中的JSON清单，
+// 在cache_ttl内且程序未更新时直接使用缓存
+func loadScriptInventory(path string, opts *InventoryConfig) (*Inventory, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.ConfigurationError, "dynamic_inventory", path, "invalid inventory path")
+	}
+	cacheFile := inventoryCacheFile(abs, opts)
+	content, ok := readInventoryCache(cacheFile, abs, opts)
+	if !ok {
+		content, err = runInventoryScript(abs, opts)
+		if err != nil {
+			return nil, err
+		}
+		writeInventoryCache(cacheFile, content, opts)
+	}
+	inv, err := parseJSONInventory(content)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.ConfigurationError, "dynamic_inventory", path, "invalid inventory output")
+	}
+	return inv, nil
+}
+
+func runInventoryScript(path string, opts *InventoryConfig) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.ScriptTimeout)*time.Second)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "--list")
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 超时后脚本启动的子进程可能仍占用输出管道，不再等待
+	cmd.WaitDelay = time.Second
+	slog.Debug("Running dynamic inventory", "Path", path)
+	if err := cmd.Run(); err != nil {
+		msg := "inventory script failed"
+		if ctx.Err() == context.DeadlineExceeded {
+			msg = fmt.Sprintf("inventory script timed out after %ds", opts.ScriptTimeout)
+		}
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			msg += ": " + s
+		}
+		return nil, xerrors.Wrap(err, xerrors.ConfigurationError, "dynamic_inventory", path, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+func inventoryCacheFile(path string, opts *InventoryConfig) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(easyssh.ExpandHome(opts.CacheDir), hex.EncodeToString(sum[:8])+".json")
+}
+
+// readInventoryCache 缓存过期或早于清单程序的修改时间时视为无效
+func readInventoryCache(cacheFile, script string, opts *InventoryConfig) ([]byte, bool) {
+	if opts.CacheTTL <= 0 {
+		return nil, false
+	}
+	info, err := os.Stat(cacheFile)
+	if err != nil || time.Since(info.ModTime()) > time.Duration(opts.CacheTTL)*time.Second {
+		return nil, false
+	}
+	if scriptInfo, err := os.Stat(script); err != nil || scriptInfo.ModTime().After(info.ModTime()) {
+		return nil, false
+	}
+	content, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return nil, false
+	}
+	slog.Debug("Using cached dynamic inventory", "Path", script, "Cache", cacheFile)
+	return content, true
+}
+
+// writeInventoryCache 清单中可能包含密码，缓存文件仅当前用户可读，写入失败不影响执行
+func writeInventoryCache(cacheFile string, content []byte, opts *InventoryConfig) {
+	if opts.CacheTTL <= 0 {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0700); err != nil {
+		slog.Warn("Failed to create inventory cache directory", "ERROR", err.Error())
+		return
+	}
+	tmp := cacheFile + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		slog.Warn("Failed to write inventory cache", "ERROR", err.Error())
+		return
+	}
+	if err := os.Rename(tmp, cacheFile); err != nil {
+		slog.Warn("Failed to write inventory cache", "ERROR", err.Error())
+	}
+}
+
+// jsonGroup 动态清单中的分组，也可以直接写作主机名称列表
+type jsonGroup struct {
+	Hosts    []string       `json:"hosts,omitempty"`
+	Vars     map[string]any `json:"vars,omitempty"`
+	Children []string       `json:"children,omitempty"`
+}
+
+// parseJSONInventory 解析与ansible动态清单相同的JSON格式：
+//
+//	{
+//	  "web": {"hosts": ["web01"], "vars": {"user": "deploy"}, "children": ["canary"]},
+//	  "db": ["db01", "db02"],
+//	  "_meta": {"hostvars": {"web01": {"address": "10.0.0.1"}}}
+//	}
+func parseJSONInventory(content []byte) (*Inventory, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("inventory output is not a JSON object: %s", err.Error())
+	}
+	var meta struct {
+		HostVars map[string]map[string]any `json:"hostvars"`
+	}
+	if m, ok := raw[inventoryMetaKey]; ok {
+		if err := json.Unmarshal(m, &meta); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", inventoryMetaKey, err.Error())
+		}
+		delete(raw, inventoryMetaKey)
+	}
+
+	b := newInventoryBuilder()
+	grouped := make(map[string]bool)
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validGroupName(name) {
+			return nil, fmt.Errorf("invalid group name %q", name)
+		}
+		var g jsonGroup
+		if err := json.Unmarshal(raw[name], &g.Hosts); err != nil {
+			g = jsonGroup{}
+			if err := json.Unmarshal(raw[name], &g); err != nil {
+				return nil, fmt.Errorf("invalid group %s: %s", name, err.Error())
+			}
+		}
+		group := b.group(name)
+		for k, v := range g.Vars {
+			group.Vars[k] = v
+		}
+		for _, child := range g.Children {
+			if err := b.addChild(name, child); err != nil {
+				return nil, fmt.Errorf("group %s: %s", name, err.Error())
+			}
+		}
+		for _, host := range g.Hosts {
+			if err := b.addHost(name, host, meta.HostVars[host], 0); err != nil {
+				return nil, fmt.Errorf("group %s: %s", name, err.Error())
+			}
+			grouped[host] = true
+		}
+	}
+	// 只出现在hostvars中的主机作为未分组主机
+	hosts := make([]string, 0, len(meta.HostVars))
+	for host := range meta.HostVars {
+		if !grouped[host] {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if err := b.addHost("", host, meta.HostVars[host], 0); err != nil {
+			return nil, err
+		}
+	}
+	return b.build()
+}
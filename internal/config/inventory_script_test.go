@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"goss/internal/xerrors"
+)
+
+const testInventoryOutput = `{
+  "web": {"hosts": ["web01", "web02"], "vars": {"user": "deploy"}},
+  "db": ["db01"],
+  "_meta": {"hostvars": {
+    "web01": {"address": "10.0.0.1", "port": 2222},
+    "bastion": {"address": "10.0.0.254"}
+  }}
+}`
+
+// writeInventoryScript 写入动态清单脚本，每次执行时向 runs 文件追加一行用于统计执行次数
+func writeInventoryScript(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "inventory.sh")
+	script := "#!/bin/sh\necho run >> \"$(dirname \"$0\")/runs\"\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func scriptRuns(t *testing.T, dir string) int {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(content), "run\n")
+}
+
+func findHost(inv *Inventory, name string) *Host {
+	for _, h := range inv.Hosts {
+		if h.Name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+func TestLoadScriptInventory(t *testing.T) {
+	dir := t.TempDir()
+	path := writeInventoryScript(t, dir, "cat <<'EOF'\n"+testInventoryOutput+"\nEOF")
+	opts := &InventoryConfig{ScriptTimeout: 5}
+
+	if !isInventoryScript(path) {
+		t.Fatalf("%s should be detected as an inventory script", path)
+	}
+	inv, err := loadScriptInventory(path, opts)
+	if err != nil {
+		t.Fatalf("loadScriptInventory: %v", err)
+	}
+	if len(inv.Hosts) != 4 {
+		var names []string
+		for _, h := range inv.Hosts {
+			names = append(names, h.Name)
+		}
+		t.Fatalf("expected 4 hosts, got %v", names)
+	}
+
+	web01 := findHost(inv, "web01")
+	if web01 == nil {
+		t.Fatal("web01 is missing")
+	}
+	if web01.IP != "10.0.0.1" || web01.Port != "2222" || web01.User != "deploy" {
+		t.Errorf("web01: got ip=%s port=%s user=%s", web01.IP, web01.Port, web01.User)
+	}
+	// 同时出现在分组与hostvars中的主机不应再作为未分组主机
+	if slices.Contains(web01.Groups, GroupUngrouped) {
+		t.Errorf("web01 should not be ungrouped, groups: %v", web01.Groups)
+	}
+	bastion := findHost(inv, "bastion")
+	if bastion == nil {
+		t.Fatal("host only listed in hostvars is missing")
+	}
+	if bastion.IP != "10.0.0.254" || !slices.Contains(bastion.Groups, GroupUngrouped) {
+		t.Errorf("bastion: got ip=%s groups=%v", bastion.IP, bastion.Groups)
+	}
+	if db01 := findHost(inv, "db01"); db01 == nil || !slices.Contains(db01.Groups, "db") {
+		t.Errorf("db01 should belong to group db")
+	}
+}
+
+func TestLoadScriptInventoryCache(t *testing.T) {
+	dir := t.TempDir()
+	path := writeInventoryScript(t, dir, "echo '{\"all\": [\"web01\"]}'")
+	opts := &InventoryConfig{
+		CacheTTL:      60,
+		CacheDir:      filepath.Join(dir, "cache"),
+		ScriptTimeout: 5,
+	}
+	// 脚本的修改时间早于缓存，保证缓存有效
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		inv, err := loadScriptInventory(path, opts)
+		if err != nil {
+			t.Fatalf("load %d: %v", i, err)
+		}
+		if len(inv.Hosts) != 1 || inv.Hosts[0].Name != "web01" {
+			t.Fatalf("load %d: unexpected hosts %v", i, inv.Hosts)
+		}
+	}
+	if runs := scriptRuns(t, dir); runs != 1 {
+		t.Fatalf("expected the second load to use the cache, script ran %d times", runs)
+	}
+
+	// 脚本更新后缓存失效
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScriptInventory(path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if runs := scriptRuns(t, dir); runs != 2 {
+		t.Fatalf("expected a modified script to invalidate the cache, script ran %d times", runs)
+	}
+}
+
+func TestLoadScriptInventoryTimeout(t *testing.T) {
+	dir := t.TempDir()
+	path := writeInventoryScript(t, dir, "sleep 10\necho '{}'")
+	opts := &InventoryConfig{ScriptTimeout: 1}
+
+	start := time.Now()
+	_, err := loadScriptInventory(path, opts)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout took %s", elapsed)
+	}
+	if !xerrors.IsType(err, xerrors.ConfigurationError) {
+		t.Errorf("expected a configuration error, got %T: %v", err, err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestLoadScriptInventoryInvalidOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := writeInventoryScript(t, dir, "echo 'not json'")
+
+	_, err := loadScriptInventory(path, &InventoryConfig{ScriptTimeout: 5})
+	if !xerrors.IsType(err, xerrors.ConfigurationError) {
+		t.Fatalf("expected a configuration error, got %v", err)
+	}
+}
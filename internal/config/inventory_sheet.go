@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 表格清单中各列默认识别的表头(不区分大小写)，可通过 inventory.columns 指定实际表头
+var sheetColumnAliases = map[string][]string{
+	"name":          {"name", "hostname", "host name"},
+	"address":       {"address", "ip", "ip address", "host"},
+	"port":          {"port", "ssh port"},
+	"user":          {"user", "username"},
+	"password":      {"password"},
+	"sudo_pass":     {"sudo_pass", "sudo password", "root password"},
+	"groups":        {"groups", "group"},
+	"identity_file": {"identity_file", "identity file"},
+	"jump":          {"jump"},
+}
+
+// isSheetFile 判断是否为带表头的表格清单：.xlsx，或首行可以识别出地址列的 .csv。
+// 没有表头的 .csv 仍按原有的 ip,user,password,sudo_pass 格式解析
+func isSheetFile(path string, content []byte, opts *InventoryConfig) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		return true
+	case ".csv":
+		r := csv.NewReader(bytes.NewReader(content))
+		r.FieldsPerRecord = -1
+		header, err := r.Read()
+		if err != nil {
+			return false
+		}
+		_, err = sheetColumnIndex(header, opts)
+		return err == nil
+	}
+	return false
+}
+
+// ImportSheet 读取 .xlsx 或带表头的 .csv 主机列表，返回由有效行构成的清单与被拒绝的行
+func ImportSheet(path string, opts *InventoryConfig) (*Inventory, InventoryErrors, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if content, err = decryptContent(content); err != nil {
+		return nil, nil, fmt.Errorf("inventory %s: %w", path, err)
+	}
+	return parseSheetInventory(path, content, opts)
+}
+
+// loadSheetInventory 作为 --hosts 加载时任一行被拒绝都视为错误
+func loadSheetInventory(path string, content []byte, opts *InventoryConfig) (*Inventory, error) {
+	inv, rejected, err := parseSheetInventory(path, content, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := rejected.err(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func parseSheetInventory(path string, content []byte, opts *InventoryConfig) (*Inventory, InventoryErrors, error) {
+	if opts != nil {
+		if err := validateSheetColumns(opts.Columns); err != nil {
+			return nil, nil, err
+		}
+	}
+	rows, err := readSheetRows(path, content, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inventory %s: %s", path, err.Error())
+	}
+	// 跳过表头之前的空行，行号仍按原始位置计算
+	rows, offset := trimLeadingRows(rows)
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("inventory %s: no header row found", path)
+	}
+	columns, err := sheetColumnIndex(rows[0], opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inventory %s: %s", path, err.Error())
+	}
+
+	b := newInventoryBuilder()
+	var rejected InventoryErrors
+	for i, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+		line := offset + i + 2
+		if err := b.addSheetRow(row, columns, line); err != nil {
+			rejected.add(line, err)
+		}
+	}
+	inv, err := b.build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return inv, rejected, nil
+}
+
+// trimLeadingRows 去掉表头之前的空行，返回去掉的行数
+func trimLeadingRows(rows [][]string) ([][]string, int) {
+	n := 0
+	for n < len(rows) && isEmptyRow(rows[n]) {
+		n++
+	}
+	return rows[n:], n
+}
+
+func readSheetRows(path string, content []byte, opts *InventoryConfig) ([][]string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		f, err := excelize.OpenReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sheet := ""
+		if opts != nil {
+			sheet = opts.Sheet
+		}
+		if sheet == "" {
+			sheet = f.GetSheetName(0)
+		}
+		return f.GetRows(sheet)
+	}
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// Excel另存的csv带有UTF-8 BOM
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
+
+// validateSheetColumns 检查列映射中的键是否为支持的主机参数
+func validateSheetColumns(columns map[string]string) error {
+	for key := range columns {
+		if _, ok := sheetColumnAliases[key]; !ok {
+			return fmt.Errorf("unknown inventory column %q, expected one of: %s", key, strings.Join(inventoryColumns, ", "))
+		}
+	}
+	return nil
+}
+
+// sheetColumnIndex 根据表头确定各主机参数所在的列，配置中指定的表头必须存在
+func sheetColumnIndex(header []string, opts *InventoryConfig) (map[string]int, error) {
+	columns := make(map[string]int)
+	for _, key := range inventoryColumns {
+		names := sheetColumnAliases[key]
+		configured := ""
+		if opts != nil {
+			configured = opts.Columns[key]
+		}
+		if configured != "" {
+			names = []string{configured}
+		}
+		idx := slices.IndexFunc(header, func(cell string) bool {
+			return slices.ContainsFunc(names, func(name string) bool {
+				return strings.EqualFold(strings.TrimSpace(cell), name)
+			})
+		})
+		if idx < 0 {
+			if configured != "" {
+				return nil, fmt.Errorf("column %q for %s not found in the header row", configured, key)
+			}
+			continue
+		}
+		columns[key] = idx
+	}
+	_, hasAddress := columns["address"]
+	_, hasName := columns["name"]
+	if !hasAddress && !hasName {
+		return nil, fmt.Errorf("no address column found in the header row, set inventory.columns.address to the header of the IP column")
+	}
+	return columns, nil
+}
+
+// addSheetRow 校验一行主机信息并加入清单，出错时不加入任何分组
+func (b *inventoryBuilder) addSheetRow(row []string, columns map[string]int, line int) error {
+	cell := func(key string) string {
+		if idx, ok := columns[key]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+	name, address := cell("name"), cell("address")
+	if name == "" {
+		name = address
+	}
+	if name == "" {
+		return fmt.Errorf("missing address")
+	}
+	vars := make(map[string]any)
+	if address != "" && address != name {
+		// 名称与地址不同时无法将两者的范围逐一对应
+		if hostRangePattern.MatchString(address) || strings.Contains(address, "/") {
+			return fmt.Errorf("address %q must be a single host when a name is given", address)
+		}
+		vars["address"] = address
+	}
+	if port := cell("port"); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		vars["port"] = port
+	}
+	for _, key := range []string{"user", "password", "sudo_pass", "identity_file", "jump"} {
+		if value := cell(key); value != "" {
+			vars[key] = value
+		}
+	}
+	groups := strings.FieldsFunc(cell("groups"), func(r rune) bool {
+		return r == ',' || r == ';' || r == ' '
+	})
+	for _, group := range groups {
+		if !validGroupName(group) {
+			return fmt.Errorf("invalid group name %q", group)
+		}
+	}
+	// 表格中同一主机出现在多行通常是录入错误，不按分组合并
+	addresses, err := expandHostPattern(name)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		if host, _, err := parseHostAddress(addr); err == nil {
+			if h, ok := b.hosts[host]; ok {
+				return fmt.Errorf("duplicate host %s (first defined at line %d)", host, h.line)
+			}
+		}
+	}
+	if len(groups) == 0 {
+		groups = []string{""}
+	}
+	for _, group := range groups {
+		if err := b.addHost(group, name, vars, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FilterHosts 按 --limit 表达式筛选主机，保持主机清单中的顺序。
+// 表达式由逗号分隔的多个模式组成：
+//
+//	web            分组名称或主机名称/地址
+//	web0*          通配符，同时匹配分组与主机
+//	~web0[1-3]$    正则表达式，同时匹配分组与主机
+//	&prod          与之前的结果取交集
+//	!web03         从结果中排除
+//	@retry.txt     从文件读取模式，每行一个
+//
+// 没有普通模式时从全部主机开始筛选，筛选结果为空时返回错误
+func FilterHosts(hosts []*Host, limit string) ([]*Host, error) {
+	terms, err := parseLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	var include, intersect, exclude []string
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(term, "!"):
+			exclude = append(exclude, term[1:])
+		case strings.HasPrefix(term, "&"):
+			intersect = append(intersect, term[1:])
+		default:
+			include = append(include, term)
+		}
+	}
+
+	selected := make([]bool, len(hosts))
+	if len(include) == 0 {
+		for i := range selected {
+			selected[i] = true
+		}
+	}
+	for _, pattern := range include {
+		matched, err := matchHosts(hosts, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hosts {
+			selected[i] = selected[i] || matched[i]
+		}
+	}
+	for _, pattern := range intersect {
+		matched, err := matchHosts(hosts, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hosts {
+			selected[i] = selected[i] && matched[i]
+		}
+	}
+	for _, pattern := range exclude {
+		matched, err := matchHosts(hosts, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hosts {
+			selected[i] = selected[i] && !matched[i]
+		}
+	}
+
+	var result []*Host
+	for i, host := range hosts {
+		if selected[i] {
+			result = append(result, host)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no hosts matched the limit %q", limit)
+	}
+	return result, nil
+}
+
+// parseLimit 拆分表达式并展开 @file，文件中的空行与#注释被忽略
+func parseLimit(limit string) ([]string, error) {
+	var terms []string
+	for _, term := range strings.Split(limit, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		file, ok := strings.CutPrefix(term, "@")
+		if !ok {
+			terms = append(terms, term)
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read limit file: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				terms = append(terms, line)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read limit file %s: %w", file, err)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty limit %q", limit)
+	}
+	return terms, nil
+}
+
+// matchHosts 返回每个主机是否匹配单个模式，模式不匹配任何主机时给出警告
+func matchHosts(hosts []*Host, pattern string) ([]bool, error) {
+	var match func(string) bool
+	switch {
+	case strings.HasPrefix(pattern, "~"):
+		re, err := regexp.Compile(pattern[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit regex %q: %s", pattern, err.Error())
+		}
+		match = re.MatchString
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid limit pattern %q", pattern)
+		}
+		match = func(s string) bool {
+			ok, _ := path.Match(pattern, s)
+			return ok
+		}
+	default:
+		match = func(s string) bool { return s == pattern }
+	}
+
+	matched := make([]bool, len(hosts))
+	found := false
+	for i, host := range hosts {
+		names := append([]string{host.DisplayName(), host.IP}, host.Groups...)
+		for _, name := range names {
+			if match(name) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		slog.Warn("Limit pattern did not match any host", "Pattern", pattern)
+	}
+	return matched, nil
+}
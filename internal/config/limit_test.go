@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterHosts(t *testing.T) {
+	hosts := []*Host{
+		{Name: "web01", IP: "10.0.0.1", Groups: []string{"web", "prod", GroupAll}},
+		{Name: "web02", IP: "10.0.0.2", Groups: []string{"web", "staging", GroupAll}},
+		{Name: "web03", IP: "10.0.0.3", Groups: []string{"web", "prod", GroupAll}},
+		{Name: "db01", IP: "10.0.1.1", Groups: []string{"db", "prod", GroupAll}},
+	}
+	limitFile := filepath.Join(t.TempDir(), "retry.txt")
+	if err := os.WriteFile(limitFile, []byte("# failed hosts\nweb02\n\ndb01\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		limit   string
+		want    []string
+		wantErr string
+	}{
+		{limit: "web", want: []string{"web01", "web02", "web03"}},
+		{limit: "db01,web02", want: []string{"web02", "db01"}},
+		{limit: "10.0.0.3", want: []string{"web03"}},
+		{limit: "web,&prod", want: []string{"web01", "web03"}},
+		{limit: "web,!web03", want: []string{"web01", "web02"}},
+		{limit: "!db", want: []string{"web01", "web02", "web03"}},
+		{limit: "&prod,!web01", want: []string{"web03", "db01"}},
+		{limit: "web0*", want: []string{"web01", "web02", "web03"}},
+		{limit: "10.0.0.?", want: []string{"web01", "web02", "web03"}},
+		{limit: "~^web0[12]$", want: []string{"web01", "web02"}},
+		{limit: "~^(db|staging)$", want: []string{"web02", "db01"}},
+		{limit: "@" + limitFile, want: []string{"web02", "db01"}},
+		{limit: "web,nosuchgroup", want: []string{"web01", "web02", "web03"}},
+		{limit: "nosuchgroup", wantErr: "no hosts matched"},
+		{limit: "web,&db", wantErr: "no hosts matched"},
+		{limit: "~web[", wantErr: "invalid limit regex"},
+		{limit: "web[", wantErr: "invalid limit pattern"},
+		{limit: " , ", wantErr: "empty limit"},
+		{limit: "@" + filepath.Join(t.TempDir(), "missing.txt"), wantErr: "failed to read limit file"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.limit, func(t *testing.T) {
+			result, err := FilterHosts(hosts, tc.limit)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, host := range result {
+				names = append(names, host.Name)
+			}
+			if !slices.Equal(names, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, names)
+			}
+		})
+	}
+}
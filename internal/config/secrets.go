@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"goss/pkg/easyssh"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 密码引用命令的执行超时
+const secretCommandTimeout = 30 * time.Second
+
+// secretResolver 解析密码引用，相同的引用只解析一次
+type secretResolver struct {
+	cache map[string]string
+}
+
+func newSecretResolver() *secretResolver {
+	return &secretResolver{cache: make(map[string]string)}
+}
+
+// resolve 解析以下形式的密码引用，其他值原样返回：
+//
+//	env:GOSS_ROOT_PASS      环境变量
+//	file:~/.secrets/root    文件内容(去掉末尾换行)
+//	cmd:pass show infra/db  命令的标准输出(去掉末尾换行)
+//
+// 以这些前缀开头的明文密码在前面加 \ 转义，例如 \cmd:abc 表示密码 cmd:abc
+func (r *secretResolver) resolve(value string) (string, error) {
+	if strings.HasPrefix(value, `\`) && isSecretRef(strings.TrimLeft(value, `\`)) {
+		return value[1:], nil
+	}
+	if !isSecretRef(value) {
+		return value, nil
+	}
+	kind, ref, _ := strings.Cut(value, ":")
+	if v, ok := r.cache[value]; ok {
+		return v, nil
+	}
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("empty %s password reference", kind)
+	}
+	var secret string
+	switch kind {
+	case "env":
+		v, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s referenced by password is not set", ref)
+		}
+		secret = v
+	case "file":
+		content, err := os.ReadFile(easyssh.ExpandHome(ref))
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		secret = strings.TrimRight(string(content), "\r\n")
+	case "cmd":
+		ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		c := exec.CommandContext(ctx, "sh", "-c", ref)
+		c.Stdout = &stdout
+		c.Stderr = &stderr
+		if err := c.Run(); err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = err.Error()
+			}
+			return "", fmt.Errorf("password command %q failed: %s", ref, msg)
+		}
+		secret = strings.TrimRight(stdout.String(), "\r\n")
+	}
+	r.cache[value] = secret
+	return secret, nil
+}
+
+// ResolveSecrets 解析主机与其经过的跳板机中 env: file: cmd: 形式的密码引用。
+// 在筛选出要连接的主机后调用，未选中的主机与 --list-hosts 不会读取密码或执行命令
+func (cfg *GossConfig) ResolveSecrets(hosts []*Host) error {
+	var errs InventoryErrors
+	secrets := newSecretResolver()
+	jumps := make(map[string]bool)
+	for _, host := range hosts {
+		if err := secrets.resolveAll(&host.Password, &host.SudoPass, &host.Passphrase); err != nil {
+			errs.add(host.Line, fmt.Errorf("host %s: %s", host.DisplayName(), err.Error()))
+		}
+		if name := cfg.HostJump(host); name != "" {
+			// 跳板机链路错误在连接时报告
+			chain, _ := cfg.JumpChain(name)
+			for _, n := range chain {
+				jumps[n] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(jumps))
+	for name := range jumps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// ~/.ssh/config生成的跳板机没有密码
+		jump, ok := cfg.JumpHosts[name]
+		if !ok || jump == nil {
+			continue
+		}
+		if err := secrets.resolveAll(&jump.Password, &jump.Passphrase); err != nil {
+			errs.add(0, fmt.Errorf("jump host %s: %s", name, err.Error()))
+		}
+	}
+	return errs.err()
+}
+
+// resolveAll 依次解析多个字段
+func (r *secretResolver) resolveAll(values ...*string) error {
+	for _, v := range values {
+		secret, err := r.resolve(*v)
+		if err != nil {
+			return err
+		}
+		*v = secret
+	}
+	return nil
+}
+
+// isSecretRef 判断是否为 env: file: cmd: 形式的密码引用
+func isSecretRef(value string) bool {
+	kind, _, ok := strings.Cut(value, ":")
+	return ok && (kind == "env" || kind == "file" || kind == "cmd")
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 由~/.ssh/config的ProxyJump生成的跳板机名称前缀，例如 ssh:bastion,inner
+const SSHJumpPrefix = "ssh:"
+
+// Include 嵌套的最大深度，防止循环引用
+const maxIncludeDepth = 16
+
+// SSHConfig OpenSSH客户端配置文件，只解析goss用到的参数
+type SSHConfig struct {
+	blocks []*sshConfigBlock
+}
+
+// sshConfigBlock 一个Host块，参数名统一转为小写并保持文件中的顺序
+type sshConfigBlock struct {
+	patterns []string
+	options  [][2]string
+}
+
+// SSHHostConfig 单个主机在ssh配置中匹配到的参数，未配置的字段为空
+type SSHHostConfig struct {
+	HostName              string
+	User                  string
+	Port                  string
+	IdentityFiles         []string
+	ProxyJump             string
+	ConnectTimeout        int
+	StrictHostKeyChecking string
+}
+
+// LoadSSHConfig 解析ssh客户端配置文件，文件不存在时返回空配置
+// 支持Host与Include，Match块仅支持 Match all，其他条件的块被忽略
+func LoadSSHConfig(file string) (*SSHConfig, error) {
+	c := &SSHConfig{}
+	// 第一个Host之前的参数对所有主机生效
+	global := &sshConfigBlock{patterns: []string{"*"}}
+	c.blocks = append(c.blocks, global)
+	err := c.parseFile(easyssh.ExpandHome(file), global, 0)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *SSHConfig) parseFile(file string, current *sshConfigBlock, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("ssh config %s: include nested too deeply", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, args, err := splitSSHConfigLine(line)
+		if err != nil {
+			return fmt.Errorf("ssh config %s: %s at line %d", file, err.Error(), lineNum)
+		}
+		switch key {
+		case "host":
+			current = &sshConfigBlock{patterns: args}
+			c.blocks = append(c.blocks, current)
+		case "match":
+			current = &sshConfigBlock{}
+			if len(args) == 1 && strings.EqualFold(args[0], "all") {
+				current.patterns = []string{"*"}
+			} else {
+				slog.Debug("Ignoring unsupported Match block in ssh config", "file", file, "line", lineNum)
+			}
+			c.blocks = append(c.blocks, current)
+		case "include":
+			for _, pattern := range args {
+				pattern = easyssh.ExpandHome(pattern)
+				// 相对路径相对于~/.ssh目录
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(easyssh.ExpandHome("~/.ssh"), pattern)
+				}
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("ssh config %s: invalid include pattern %q at line %d", file, pattern, lineNum)
+				}
+				for _, m := range matches {
+					if err := c.parseFile(m, current, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			current.options = append(current.options, [2]string{key, strings.Join(args, " ")})
+		}
+	}
+	return scanner.Err()
+}
+
+// splitSSHConfigLine 拆分 "Keyword value" 或 "Keyword=value" 形式的配置行，参数支持双引号
+func splitSSHConfigLine(line string) (string, []string, error) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return "", nil, fmt.Errorf("missing value for %q", line)
+	}
+	key := strings.ToLower(line[:i])
+	rest := strings.TrimSpace(line[i:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+	var (
+		args   []string
+		cur    strings.Builder
+		quoted bool
+		hasArg bool
+	)
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			hasArg = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if hasArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if quoted {
+		return "", nil, fmt.Errorf("unterminated quote")
+	}
+	if hasArg {
+		args = append(args, cur.String())
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("missing value for %q", key)
+	}
+	return key, args, nil
+}
+
+// matches 判断主机别名是否匹配Host块的模式，任一否定模式(!pattern)匹配时不生效
+func (b *sshConfigBlock) matches(alias string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		negate := strings.HasPrefix(p, "!")
+		ok, _ := path.Match(strings.TrimPrefix(p, "!"), alias)
+		if ok && negate {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
+
+// Lookup 按OpenSSH规则查找主机参数：同一参数以第一次出现的值为准，IdentityFile累加
+func (c *SSHConfig) Lookup(alias string) *SSHHostConfig {
+	h := &SSHHostConfig{}
+	if c == nil {
+		return h
+	}
+	seen := make(map[string]bool)
+	for _, b := range c.blocks {
+		if !b.matches(alias) {
+			continue
+		}
+		for _, opt := range b.options {
+			key, value := opt[0], opt[1]
+			if key == "identityfile" {
+				h.IdentityFiles = append(h.IdentityFiles, value)
+				continue
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			switch key {
+			case "hostname":
+				h.HostName = expandSSHTokens(value, map[byte]string{'h': alias})
+			case "user":
+				h.User = value
+			case "port":
+				h.Port = value
+			case "proxyjump":
+				h.ProxyJump = value
+			case "connecttimeout":
+				h.ConnectTimeout, _ = strconv.Atoi(value)
+			case "stricthostkeychecking":
+				h.StrictHostKeyChecking = strings.ToLower(value)
+			}
+		}
+	}
+	return h
+}
+
+// identityFile 返回第一个存在的IdentityFile，与OpenSSH一样跳过不存在的文件
+func (h *SSHHostConfig) identityFile(hostname, remoteUser string) string {
+	home, _ := os.UserHomeDir()
+	for _, f := range h.IdentityFiles {
+		f = expandSSHTokens(f, map[byte]string{'d': home, 'u': localUser(), 'h': hostname, 'r': remoteUser})
+		f = easyssh.ExpandHome(f)
+		if _, err := os.Stat(f); err == nil {
+			return f
+		}
+	}
+	return ""
+}
+
+// expandSSHTokens 展开 %h %r %u %d 等ssh配置中的替换符
+func expandSSHTokens(s string, tokens map[byte]string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if s[i] == '%' {
+			b.WriteByte('%')
+		} else if v, ok := tokens[s[i]]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// sshSecurityMode 将StrictHostKeyChecking转换为security_mode
+func sshSecurityMode(value string) (int, error) {
+	switch value {
+	case "no", "off":
+		return 0, nil
+	case "ask":
+		return 1, nil
+	case "yes":
+		return 2, nil
+	case "accept-new":
+		return 3, nil
+	}
+	return 0, fmt.Errorf("unsupported StrictHostKeyChecking value %q", value)
+}
+
+// applySSHConfig 将ssh配置合并到主机，主机清单中显式配置的值优先
+func (cfg *GossConfig) applySSHConfig(host *Host) error {
+	alias := host.DisplayName()
+	sc := cfg.sshConfig.Lookup(alias)
+	// 清单中通过address指定了地址时不使用HostName
+	if sc.HostName != "" && (host.Name == "" || host.IP == host.Name) {
+		host.IP = sc.HostName
+	}
+	if host.User == "" {
+		host.User = sc.User
+	}
+	if host.Port == "" && sc.Port != "" {
+		if _, err := strconv.Atoi(sc.Port); err != nil {
+			return fmt.Errorf("invalid Port %q in ssh config", sc.Port)
+		}
+		host.Port = sc.Port
+	}
+	if host.IdentityFile == "" {
+		host.IdentityFile = sc.identityFile(host.IP, host.User)
+	}
+	if host.Jump == "" && sc.ProxyJump != "" {
+		if strings.EqualFold(sc.ProxyJump, "none") {
+			host.Jump = NoJump
+		} else {
+			host.Jump = SSHJumpPrefix + sc.ProxyJump
+		}
+	}
+	if host.ConnectTimeout == 0 {
+		host.ConnectTimeout = sc.ConnectTimeout
+	}
+	if host.SecurityMode == nil && sc.StrictHostKeyChecking != "" {
+		mode, err := sshSecurityMode(sc.StrictHostKeyChecking)
+		if err != nil {
+			return fmt.Errorf("%s in ssh config", err.Error())
+		}
+		host.SecurityMode = &mode
+	}
+	return nil
+}
+
+// sshJumpHost 根据ProxyJump生成跳板机定义，例如 ssh:bastion 或 ssh:jump@10.0.0.1:2222,inner
+// 多个跳板机时最后一个为直接连接目标主机的跳板机，其余部分作为上一跳
+func (cfg *GossConfig) sshJumpHost(name string) (*JumpHost, bool) {
+	spec, ok := strings.CutPrefix(name, SSHJumpPrefix)
+	if !ok {
+		return nil, false
+	}
+	hops := strings.Split(spec, ",")
+	last := strings.TrimSpace(hops[len(hops)-1])
+	last = strings.TrimPrefix(last, "ssh://")
+	if last == "" {
+		return nil, false
+	}
+	jumpUser, hostPort := "", last
+	if i := strings.LastIndex(last, "@"); i >= 0 {
+		jumpUser, hostPort = last[:i], last[i+1:]
+	}
+	alias, port := hostPort, ""
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		alias, port = h, p
+	}
+	sc := cfg.sshConfig.Lookup(alias)
+	jump := &JumpHost{Address: alias, User: jumpUser, Port: cfg.Connection.DefaultPort}
+	if sc.HostName != "" {
+		jump.Address = sc.HostName
+	}
+	if jump.User == "" {
+		jump.User = sc.User
+	}
+	if jump.User == "" {
+		jump.User = localUser()
+	}
+	if port == "" {
+		port = sc.Port
+	}
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, false
+		}
+		jump.Port = p
+	}
+	jump.IdentityFile = sc.identityFile(jump.Address, jump.User)
+	if len(hops) > 1 {
+		jump.Jump = SSHJumpPrefix + strings.Join(hops[:len(hops)-1], ",")
+	} else if sc.ProxyJump != "" && !strings.EqualFold(sc.ProxyJump, "none") {
+		// 跳板机自身在ssh配置中的ProxyJump
+		jump.Jump = SSHJumpPrefix + sc.ProxyJump
+	}
+	return jump, true
+}
+
+// localUser 当前系统用户名，主机未配置用户时与ssh一样使用该用户登录
+func localUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
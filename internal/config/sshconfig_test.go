@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplySSHConfigHostName(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ssh_config")
+	content := "Host db01 web01\n  HostName 192.168.1.10\n  User deploy\n  Port 2222\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshConfig, err := LoadSSHConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &GossConfig{sshConfig: sshConfig}
+
+	cases := []struct {
+		name   string
+		host   *Host
+		wantIP string
+	}{
+		{name: "alias without address", host: &Host{Name: "db01", IP: "db01"}, wantIP: "192.168.1.10"},
+		{name: "explicit address wins", host: &Host{Name: "web01", IP: "10.0.0.5"}, wantIP: "10.0.0.5"},
+		{name: "unmatched host", host: &Host{Name: "app01", IP: "app01"}, wantIP: "app01"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := cfg.applySSHConfig(tc.host); err != nil {
+				t.Fatal(err)
+			}
+			if tc.host.IP != tc.wantIP {
+				t.Errorf("expected IP %s, got %s", tc.wantIP, tc.host.IP)
+			}
+		})
+	}
+}
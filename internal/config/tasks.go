@@ -1,9 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"goss/internal/utils"
+	"goss/pkg/easyssh"
 	"log/slog"
+	"os"
 
 	"github.com/spf13/viper"
 )
@@ -15,6 +18,8 @@ const (
 	SCRIPT   TaskType = "script"
 	UPLOAD   TaskType = "upload"
 	DOWNLOAD TaskType = "download"
+	// 采集主机信息，之后的任务可在模板中引用 {{ .Facts }}
+	FACTS TaskType = "facts"
 )
 
 type Task struct {
@@ -22,17 +27,31 @@ type Task struct {
 	Description string   `mapstructure:"description"`
 	Cmd         string   `mapstructure:"cmd"`
 	RequireSudo bool     `mapstructure:"require_sudo"`
-	Local       string   `mapstructure:"local"`
-	Remote      string   `mapstructure:"remote"`
+	// 提权方式与目标用户，为空时使用主机清单中的 become_method/become_user，仅在require_sudo为true时生效
+	BecomeMethod string `mapstructure:"become_method"`
+	BecomeUser   string `mapstructure:"become_user"`
+	Local        string `mapstructure:"local"`
+	Remote       string `mapstructure:"remote"`
+	// 将本地ssh-agent转发到远端会话，仅对cmd和script任务生效
+	ForwardAgent bool `mapstructure:"forward_agent"`
+	// 执行前使用主机变量渲染cmd，例如 {{ .Vars.app_dir }}，默认关闭以免与命令中的 {{ }} 冲突
+	Template bool `mapstructure:"template"`
 }
 
 // LoadTasks 加载任务配置
 func LoadTasks(configPath string) ([]*Task, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task configuration: %w", err)
+	}
+	// 整体加密的任务文件先解密
+	if content, err = decryptContent(content); err != nil {
+		return nil, fmt.Errorf("failed to read task configuration: %w", err)
+	}
 	v := viper.New()
-	v.SetConfigFile(configPath)
 	v.SetConfigType("yaml")
 
-	if err := v.ReadInConfig(); err != nil {
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
 		return nil, fmt.Errorf("failed to read task configuration: %w", err)
 	}
 
@@ -41,6 +60,13 @@ func LoadTasks(configPath string) ([]*Task, error) {
 		return nil, fmt.Errorf("failed to parse task configuration: %w", err)
 	}
 
+	// 解密任务中的单个加密值
+	for i, task := range tasks {
+		if err := decryptValues(&task.Cmd, &task.Local, &task.Remote); err != nil {
+			return nil, fmt.Errorf("failed to decrypt task %d: %w", i+1, err)
+		}
+	}
+
 	// 调用纠错框架
 	if err := ValidateTasks(tasks); err != nil {
 		return nil, fmt.Errorf("task configuration validation failed: %s", err.Error())
@@ -53,22 +79,31 @@ func LoadTasks(configPath string) ([]*Task, error) {
 func ValidateTasks(tasks []*Task) error {
 	for i, task := range tasks {
 		if task.Local != "" {
-			_, err := utils.RenderPathTemplate(task.Local, "127.0.0.1")
-			if err != nil {
+			if err := utils.ValidateTemplate(task.Local); err != nil {
 				return fmt.Errorf("template rendering failed local %s %s. Index %d", task.Local, err, i)
 			}
 		}
 
 		// 处理Remote路径
 		if task.Remote != "" {
-			_, err := utils.RenderPathTemplate(task.Remote, "127.0.0.1")
-			if err != nil {
+			if err := utils.ValidateTemplate(task.Remote); err != nil {
 				return fmt.Errorf("template rendering failed remote %s %s. Index %d", task.Remote, err, i)
 			}
 		}
+		if task.Template {
+			if err := utils.ValidateTemplate(task.Cmd); err != nil {
+				return fmt.Errorf("template rendering failed cmd %s %s. Index %d", task.Cmd, err, i)
+			}
+		}
 		if task.Description == "" {
 			return fmt.Errorf("the task description is mandatory. Index %d", i+1)
 		}
+		if task.BecomeMethod != "" && !easyssh.ValidBecomeMethod(task.BecomeMethod) {
+			return fmt.Errorf("invalid become_method %q, must be one of su, sudo, doas, none. Index %d", task.BecomeMethod, i+1)
+		}
+		if (task.BecomeMethod != "" || task.BecomeUser != "") && !task.RequireSudo {
+			return fmt.Errorf("become_method and become_user only take effect with require_sudo: true. Index %d", i+1)
+		}
 		switch task.Type {
 		case CMD:
 			if task.Cmd == "" {
@@ -93,6 +128,8 @@ func ValidateTasks(tasks []*Task) error {
 			if task.Local == "" {
 				return fmt.Errorf("the 'local' parameter of the upload task cannot be empty. Index %d", i+1)
 			}
+		case FACTS:
+			// 不需要参数
 		case DOWNLOAD:
 			if task.Local == "" {
 				slog.Warn("The 'local' parameter for the download task is empty, the default path will be used.", slog.String("local", DefaultDownloadDir))
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"goss/internal/vault"
+	"sync"
+)
+
+// VaultPasswordFunc 返回vault口令，只在遇到加密内容时调用一次
+type VaultPasswordFunc func() ([]byte, error)
+
+var (
+	vaultMu       sync.Mutex
+	vaultPassword VaultPasswordFunc
+	vaultKeyring  *vault.Keyring
+)
+
+// SetVaultPassword 设置加载主机清单、任务与全局配置时使用的vault口令来源
+func SetVaultPassword(fn VaultPasswordFunc) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	vaultPassword = fn
+	vaultKeyring = nil
+}
+
+func getVaultKeyring() (*vault.Keyring, error) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	if vaultKeyring != nil {
+		return vaultKeyring, nil
+	}
+	if vaultPassword == nil {
+		return nil, fmt.Errorf("vault encrypted content found but no vault password was provided")
+	}
+	password, err := vaultPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault password: %w", err)
+	}
+	keyring, err := vault.NewKeyring(password)
+	if err != nil {
+		return nil, err
+	}
+	vaultKeyring = keyring
+	return keyring, nil
+}
+
+// decryptContent 解密整体加密的文件，未加密的内容原样返回
+func decryptContent(content []byte) ([]byte, error) {
+	if !vault.IsEncrypted(content) {
+		return content, nil
+	}
+	keyring, err := getVaultKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Decrypt(content)
+}
+
+// decryptValue 解密单个加密值，未加密的值原样返回
+func decryptValue(value string) (string, error) {
+	if !vault.IsEncryptedValue(value) {
+		return value, nil
+	}
+	keyring, err := getVaultKeyring()
+	if err != nil {
+		return "", err
+	}
+	return keyring.DecryptValue(value)
+}
+
+// decryptValues 依次解密多个字段
+func decryptValues(values ...*string) error {
+	for _, v := range values {
+		plain, err := decryptValue(*v)
+		if err != nil {
+			return err
+		}
+		*v = plain
+	}
+	return nil
+}
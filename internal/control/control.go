@@ -0,0 +1,150 @@
+package control
+
+/*
+goss agent 控制套接字协议
+后台进程在本地unix套接字上保持已认证的ssh连接，exec/apply通过该套接字提交任务，
+类似于OpenSSH的ControlMaster。每个请求使用一个独立连接，请求与响应均为一行JSON
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goss/internal/config"
+	"goss/internal/model"
+	"goss/pkg/easyssh"
+	"goss/pkg/report"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+type Op string
+
+const (
+	OpPing     Op = "ping"     // 探测后台进程状态
+	OpConnect  Op = "connect"  // 建立或复用主机连接
+	OpRun      Op = "run"      // 在主机上执行任务
+	OpShutdown Op = "shutdown" // 停止后台进程
+)
+
+// 建立本地套接字连接的超时时间
+const dialTimeout = time.Second
+
+// Request 客户端请求
+type Request struct {
+	Op   Op           `json:"op"`
+	Host *config.Host `json:"host,omitempty"`
+	Task *config.Task `json:"task,omitempty"`
+	// 客户端的执行与传输配置，连接相关配置以后台进程为准
+	Execution    *config.ExecutionConfig    `json:"execution,omitempty"`
+	FileTransfer *config.FileTransferConfig `json:"file_transfer,omitempty"`
+	// 客户端工作目录，用于解析任务中的相对本地路径
+	Cwd string `json:"cwd,omitempty"`
+}
+
+// Response 后台进程响应
+type Response struct {
+	// 请求本身无法处理时的错误
+	Error string `json:"error,omitempty"`
+	// 错误分类，对应xerrors.ErrorType
+	ErrorType string `json:"error_type,omitempty"`
+	// 连接信息
+	Conn *model.ConnInfo `json:"conn,omitempty"`
+	// 任务执行结果，错误保留分类与原始错误链
+	StdOut   string        `json:"stdout,omitempty"`
+	StdErr   string        `json:"stderr,omitempty"`
+	ExitCode *int          `json:"exit_code,omitempty"`
+	Signal   string        `json:"signal,omitempty"`
+	TaskErr  *report.Error `json:"task_error,omitempty"`
+	// ping的状态信息
+	Pid         int   `json:"pid,omitempty"`
+	Connections int   `json:"connections,omitempty"`
+	Uptime      int64 `json:"uptime,omitempty"`
+}
+
+// Handler 处理单个请求
+type Handler func(req *Request) *Response
+
+// SocketPath 展开套接字路径中的 ~
+func SocketPath(path string) string {
+	return easyssh.ExpandHome(path)
+}
+
+// Listen 在指定路径创建仅当前用户可访问的unix套接字
+func Listen(path string) (net.Listener, error) {
+	path = SocketPath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	// 已有后台进程在监听时拒绝启动，否则清理残留的套接字文件
+	if Ping(path) == nil {
+		return nil, fmt.Errorf("goss agent is already running on %s", path)
+	}
+	os.Remove(path)
+	// 创建时即限制权限，避免chmod之前其他用户连接套接字
+	mask := syscall.Umask(0077)
+	l, err := net.Listen("unix", path)
+	syscall.Umask(mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to restrict control socket permissions: %w", err)
+	}
+	return l, nil
+}
+
+// Serve 处理控制套接字上的请求，直到listener关闭
+func Serve(l net.Listener, h Handler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			var req Request
+			if err := json.NewDecoder(conn).Decode(&req); err != nil {
+				slog.Warn("Invalid control request", "error", err)
+				return
+			}
+			if err := json.NewEncoder(conn).Encode(h(&req)); err != nil {
+				slog.Warn("Failed to write control response", "op", req.Op, "error", err)
+			}
+		}()
+	}
+}
+
+// Call 向后台进程发送请求并等待响应
+func Call(path string, req *Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(path), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send control request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read control response: %w", err)
+	}
+	if resp.Error != "" {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Ping 检查后台进程是否可用
+func Ping(path string) error {
+	_, err := Call(path, &Request{Op: OpPing})
+	return err
+}
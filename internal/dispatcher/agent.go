@@ -0,0 +1,93 @@
+package dispatcher
+
+import (
+	"goss/internal/config"
+	"goss/internal/control"
+	"goss/internal/pool"
+	"goss/internal/xerrors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServeAgent 运行goss agent后台服务，在控制套接字上复用已认证的连接执行任务
+// 连接相关配置以cfg为准，连接闲置超过control_persist后被回收
+func ServeAgent(l net.Listener, cfg *config.GossConfig) error {
+	agentCfg := *cfg
+	conn := *cfg.Connection
+	conn.IdleTimeout = cfg.Connection.ControlPersist
+	agentCfg.Connection = &conn
+	connPool := pool.New(&agentCfg)
+	defer connPool.Close()
+
+	startTime := time.Now()
+	slog.Info("goss agent started", "socket", l.Addr().String(), "persist", conn.IdleTimeout)
+	return control.Serve(l, func(req *control.Request) *control.Response {
+		switch req.Op {
+		case control.OpPing:
+			return &control.Response{
+				Pid:         os.Getpid(),
+				Connections: connPool.Len(),
+				Uptime:      int64(time.Since(startTime).Seconds()),
+			}
+		case control.OpConnect:
+			if req.Host == nil {
+				return &control.Response{Error: "host is required"}
+			}
+			e := &poolExecutor{pool: connPool, cfg: &agentCfg}
+			info, err := e.connect(req.Host)
+			if err != nil {
+				resp := &control.Response{Error: err.Error()}
+				if gerr, ok := err.(*xerrors.GossError); ok {
+					resp.ErrorType = string(gerr.Type)
+				}
+				return resp
+			}
+			return &control.Response{Conn: info}
+		case control.OpRun:
+			if req.Host == nil || req.Task == nil || req.Execution == nil || req.FileTransfer == nil {
+				return &control.Response{Error: "host, task and execution settings are required"}
+			}
+			// 执行与传输参数使用客户端的配置
+			runCfg := agentCfg
+			runCfg.Execution = req.Execution
+			runCfg.FileTransfer = req.FileTransfer
+			task := *req.Task
+			task.Local = resolveLocalPath(req.Cwd, task.Local)
+			result := ExecuteTask(connPool, req.Host, &task, &runCfg)
+			return &control.Response{
+				StdOut:   result.StdOut,
+				StdErr:   result.StdErr,
+				ExitCode: result.ExitCode,
+				Signal:   result.Signal,
+				TaskErr:  xerrors.ToReport(result.Err),
+			}
+		case control.OpShutdown:
+			slog.Info("goss agent is shutting down")
+			// 先返回响应再关闭监听
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				l.Close()
+			}()
+			return &control.Response{Pid: os.Getpid()}
+		default:
+			return &control.Response{Error: "unknown operation " + string(req.Op)}
+		}
+	})
+}
+
+// resolveLocalPath 本地路径相对于客户端工作目录解析
+func resolveLocalPath(cwd, p string) string {
+	if p == "" || cwd == "" || filepath.IsAbs(p) {
+		return p
+	}
+	abs := filepath.Join(cwd, p)
+	// 保留目录语义的结尾斜杠
+	if strings.HasSuffix(p, "/") {
+		abs += "/"
+	}
+	return abs
+}
@@ -10,7 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"goss/internal/config"
+	"goss/internal/facts"
 	"goss/internal/model"
+	"goss/internal/pool"
 	"goss/internal/printer"
 	"goss/internal/transfer"
 	"goss/internal/utils"
@@ -19,12 +21,9 @@ import (
 	"log/slog"
 	"runtime/debug"
 	"sort"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"golang.org/x/crypto/ssh"
 )
 
 func Run(hosts []*config.Host, tasks []*config.Task, cfg *config.GossConfig, save string) {
@@ -50,6 +49,11 @@ func Run(hosts []*config.Host, tasks []*config.Task, cfg *config.GossConfig, sav
 	var wg sync.WaitGroup
 	stopProgress := make(chan struct{})
 	defer close(stopProgress)
+	// 连接池，每个主机复用一个ssh/sftp连接，跳板机连接在所有主机间共享
+	// goss agent在运行时改为复用后台进程持有的连接
+	exec := newExecutor(cfg)
+	defer exec.close()
+	cache := newFactsCache(cfg)
 	// 处理每个主机
 	for i, host := range hosts {
 		wg.Add(1)
@@ -60,11 +64,12 @@ func Run(hosts []*config.Host, tasks []*config.Task, cfg *config.GossConfig, sav
 				wg.Done()
 			}()
 			// 为主机运行任务
-			results := taskRun(host, tasks, cfg, i, &completedTasks, &failedTasks)
+			info, results := taskRun(host, tasks, cfg, exec, cache, i, &completedTasks, &failedTasks)
 			// 创建结果收集结构体
 			resultCh <- model.HostTask{
 				Index:   i,
-				HostIP:  host.IP,
+				HostIP:  host.DisplayName(),
+				Conn:    info,
 				Results: results,
 			}
 		}(host, i)
@@ -96,12 +101,12 @@ func Run(hosts []*config.Host, tasks []*config.Task, cfg *config.GossConfig, sav
 	printer.PrintResults(HostTasks, printer.Format(save))
 }
 
-func taskRun(host *config.Host, tasks []*config.Task, cfg *config.GossConfig, goroutineID int, completedTasks, failedTasks *int32) []*model.TaskResult {
+func taskRun(host *config.Host, tasks []*config.Task, cfg *config.GossConfig, exec executor, cache *facts.Cache, goroutineID int, completedTasks, failedTasks *int32) (info *model.ConnInfo, results []*model.TaskResult) {
 	defer func() {
 		if r := recover(); r != nil {
 			slog.Error("Task coroutine crashed",
 				"goroutine", goroutineID,
-				"host", host.IP,
+				"host", host.DisplayName(),
 				"error", r,
 				"stack", string(debug.Stack()))
 			atomic.AddInt32(failedTasks, int32(len(tasks)))
@@ -109,147 +114,169 @@ func taskRun(host *config.Host, tasks []*config.Task, cfg *config.GossConfig, go
 	}()
 
 	isConnectedSuccessfully := true
-	client, createSSHErr := easyssh.NewClient(easyssh.Opts{
-		IP:             host.IP,
-		Port:           host.Port,
-		User:           host.User,
-		Passwd:         host.Password,
-		ConnectTimeout: cfg.Connection.ConnectTimeout,
-		Mode:           easyssh.SecurityMode(cfg.Connection.SecurityMode),
-	})
-	if createSSHErr != nil {
+	var connErr *xerrors.GossError
+	info, createSSHErr := exec.connect(host)
+	if createSSHErr == nil {
+		slog.Info("SSH connection established",
+			"Worker", goroutineID,
+			"Host", host.DisplayName(),
+			"AuthMethod", info.AuthMethod,
+			"KeyExchange", info.KeyExchange,
+			"Cipher", info.Cipher)
+	} else {
 		isConnectedSuccessfully = false
-		wrappedErr := xerrors.ConnectionErr(
-			"ssh_connect",
-			host.IP,
-			createSSHErr,
-		).WithDetails(map[string]interface{}{
-			"port":     host.Port,
-			"timeout":  cfg.Connection.ConnectTimeout,
-			"attempts": 1,
+		// 连接池返回的错误已按拒绝连接、超时、认证失败等类型分类
+		wrappedErr, ok := createSSHErr.(*xerrors.GossError)
+		if !ok {
+			wrappedErr = xerrors.ClassifyConnErr("ssh_connect", host.DisplayName(), createSSHErr)
+		}
+		wrappedErr = wrappedErr.WithDetails(map[string]interface{}{
+			"port":    host.Port,
+			"jump":    host.Jump,
+			"timeout": host.Timeout(cfg.Connection),
 		})
+		connErr = wrappedErr
 
 		slog.Error("SSH connection failed",
-			"host", host.IP,
+			"host", host.DisplayName(),
+			"ErrorType", wrappedErr.Type,
 			"error", wrappedErr,
 			"details", wrappedErr.Details)
 	}
-	var (
-		canProceed bool
-		results    []*model.TaskResult
-	)
+	// 开启facts.gather时在执行任务前采集主机信息，失败时引用 .Facts 的模板会在渲染时报错
+	if isConnectedSuccessfully && cfg.Facts.Gather {
+		if f, err := gatherFacts(exec, host, cache, false); err != nil {
+			slog.Warn("Failed to gather facts", "Host", host.DisplayName(), "error", err)
+		} else {
+			slog.Info("Facts gathered", "Host", host.DisplayName(), "Facts", f.Summary())
+		}
+	}
+	var canProceed bool
 
 	for _, task := range tasks {
 		taskStartTime := time.Now()
 		var result *model.TaskResult
 
 		if !isConnectedSuccessfully {
-			results = append(results, &model.TaskResult{
-				Task:   *task,
-				StdErr: fmt.Errorf("the task cannot proceed due to the inability to establish an SSH connection. " + createSSHErr.Error()),
-			})
+			result = &model.TaskResult{
+				Task: *task,
+				Err: xerrors.Wrap(connErr, connErr.Type,
+					"run_task",
+					host.DisplayName(),
+					"the task cannot proceed due to the inability to establish an SSH connection"),
+			}
+			result.SetTiming(taskStartTime, taskStartTime)
+			results = append(results, result)
 			continue
 		}
 		if shouldSkipTask(cfg, canProceed) {
 			result = &model.TaskResult{
-				Task:   *task,
-				StdErr: errors.New("the pre-task execution failed, the current task will be skipped"),
+				Task: *task,
+				Err:  errors.New("the pre-task execution failed, the current task will be skipped"),
 			}
+			result.SetTiming(taskStartTime, taskStartTime)
 			results = append(results, result)
 			continue
 		}
-		switch task.Type {
-		case config.CMD:
-			result = command(client, cfg.Execution.TaskTimeout, host.SudoPass, *task)
-		case config.SCRIPT:
-			result = script(client, cfg.Execution.TaskTimeout, host.SudoPass, *task)
-		case config.UPLOAD:
-			result = upload(client, cfg.FileTransfer.TransferTimeout, *task, cfg.FileTransfer.Retries, cfg.FileTransfer.OverwritePolicy)
-		case config.DOWNLOAD:
-			result = download(client, cfg.FileTransfer.TransferTimeout, *task, cfg.FileTransfer.Retries, cfg.FileTransfer.OverwritePolicy)
+		if task.Type == config.FACTS {
+			result = factsTask(exec, host, task, cache)
+		} else {
+			result = exec.run(host, task)
 		}
+		result.SetTiming(taskStartTime, time.Now())
 		// 输出任务结果
-		if result.StdErr != nil {
-			if gerr, ok := result.StdErr.(*xerrors.GossError); ok {
+		if result.Err != nil {
+			if gerr, ok := result.Err.(*xerrors.GossError); ok {
 				slog.Error("Task failed",
 					"Worker", goroutineID,
-					"Host", host.IP,
+					"Host", host.DisplayName(),
 					"Task", task.Description,
 					"ErrorType", gerr.Type,
 					"Error", gerr.Error(),
 					"Details", gerr.Details,
-					"Time-consuming", time.Since(taskStartTime).Round(time.Millisecond))
+					"Time-consuming", result.Duration.Round(time.Millisecond))
 			} else {
 				slog.Error("Task failed",
 					"Worker", goroutineID,
-					"Host", host.IP,
+					"Host", host.DisplayName(),
 					"Task", task.Description,
-					"Error", result.StdErr,
-					"Time-consuming", time.Since(taskStartTime).Round(time.Millisecond))
+					"Error", result.Err,
+					"Time-consuming", result.Duration.Round(time.Millisecond))
 			}
 			atomic.AddInt32(failedTasks, 1)
 		} else {
 			slog.Info("Task completed",
 				"Worker", goroutineID,
-				"Host", host.IP,
+				"Host", host.DisplayName(),
 				"Task", task.Description,
-				"Time-consuming", time.Since(taskStartTime).Round(time.Millisecond))
+				"Time-consuming", result.Duration.Round(time.Millisecond))
 			atomic.AddInt32(completedTasks, 1)
 		}
 		// 收集所有结果
 		results = append(results, result)
 	}
-	return results
+	return info, results
 }
 
-func command(client *ssh.Client, timeout int, passwd string, task config.Task) *model.TaskResult {
+func command(conn *pool.Conn, timeout int, become easyssh.Become, task config.Task) *model.TaskResult {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
 	defer cancel()
+	client, err := conn.SSH()
+	if err != nil {
+		return &model.TaskResult{
+			Task: task,
+			Err:  xerrors.ConnectionErr("create_session", task.Description, err),
+		}
+	}
 	// 创建session
-	exe, err := easyssh.NewCTXSession(ctx, client)
+	exe, err := easyssh.NewCTXSession(ctx, client.Client)
 	if err != nil {
 		return &model.TaskResult{
 			Task: task,
-			StdErr: xerrors.Wrap(err, xerrors.ExecutionError,
+			Err: xerrors.Wrap(err, xerrors.ExecutionError,
 				"create_session",
 				task.Description,
 				"failed to create SSH session"),
 		}
 	}
-	var out string
-	var execErr error
-
-	if task.RequireSudo {
-		out, execErr = exe.ExecutePrivilegedCommandOverSSH(task.Cmd, passwd)
-	} else {
-		out, execErr = exe.Execute(task.Cmd)
+	if task.ForwardAgent {
+		if err := client.ForwardAgent(exe.Session); err != nil {
+			exe.Close()
+			return &model.TaskResult{
+				Task: task,
+				Err: xerrors.Wrap(err, xerrors.ExecutionError,
+					"forward_agent",
+					task.Description,
+					"failed to forward ssh-agent"),
+			}
+		}
 	}
+	return runCommand(exe, become, task, task.Description)
+}
 
-	if execErr != nil {
+func script(conn *pool.Conn, host string, timeout int, become easyssh.Become, task config.Task) *model.TaskResult {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
+	defer cancel()
+	client, err := conn.SSH()
+	if err != nil {
 		return &model.TaskResult{
 			Task: task,
-			StdErr: xerrors.Wrap(execErr, xerrors.ExecutionError,
-				"execute_command",
-				task.Cmd,
-				"command execution failed"),
+			Err:  xerrors.ConnectionErr("create_session", task.Description, err),
 		}
 	}
-
-	return &model.TaskResult{
-		Task:   task,
-		StdOut: out,
+	sftpClient, err := conn.SFTP()
+	if err != nil {
+		return &model.TaskResult{
+			Task: task,
+			Err:  fmt.Errorf("script transfer failed. %s", err.Error()),
+		}
 	}
-}
-
-func script(client *ssh.Client, timeout int, passwd string, task config.Task) *model.TaskResult {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeout))
-	defer cancel()
 	// 传输文件到目标服务器
-	t, err := transfer.NewTransferHandler(task.Remote, task.Local, config.Always, client)
+	t, err := transfer.NewTransferHandler(task.Remote, task.Local, config.Always, sftpClient, host)
 	if err != nil {
 		return &model.TaskResult{
 			Task:   task,
-			StdErr: fmt.Errorf("script transfer failed. %s", err.Error()),
+			Err:    fmt.Errorf("script transfer failed. %s", err.Error()),
 			StdOut: "",
 		}
 	}
@@ -257,68 +284,107 @@ func script(client *ssh.Client, timeout int, passwd string, task config.Task) *m
 	_, err = t.Upload(ctx)
 	if err != nil {
 		return &model.TaskResult{
-			StdErr: fmt.Errorf("script transfer failed. %s", err.Error()),
+			Task:   task,
+			Err:    fmt.Errorf("script transfer failed. %s", err.Error()),
 			StdOut: "",
 		}
 	}
 	// 执行命令
-	exe, err := easyssh.NewCTXSession(ctx, client)
+	exe, err := easyssh.NewCTXSession(ctx, client.Client)
 	if err != nil {
 		return &model.TaskResult{
 			Task:   task,
-			StdErr: err,
+			Err:    err,
 			StdOut: "",
 		}
 	}
-	if task.RequireSudo {
-		out, err := exe.ExecutePrivilegedCommandOverSSH(task.Cmd, passwd)
-		return &model.TaskResult{
-			Task:   task,
-			StdErr: err,
-			StdOut: out,
+	if task.ForwardAgent {
+		if err := client.ForwardAgent(exe.Session); err != nil {
+			exe.Close()
+			return &model.TaskResult{
+				Task: task,
+				Err:  err,
+			}
 		}
+	}
+	return runCommand(exe, become, task, host)
+}
+
+// runCommand 执行命令并记录输出与退出状态，提权失败、超时与非零退出分别归类
+func runCommand(exe *easyssh.CtxSession, become easyssh.Become, task config.Task, target string) *model.TaskResult {
+	var (
+		res *easyssh.Result
+		err error
+	)
+	if task.RequireSudo {
+		res, err = exe.ExecuteBecome(task.Cmd, become)
 	} else {
-		out, err := exe.Execute(task.Cmd)
-		return &model.TaskResult{
-			Task:   task,
-			StdErr: err,
-			StdOut: out,
-		}
+		res, err = exe.Execute(task.Cmd)
 	}
+	result := &model.TaskResult{
+		Task:     task,
+		StdOut:   res.Stdout,
+		StdErr:   res.Stderr,
+		ExitCode: res.ExitCode,
+		Signal:   res.Signal,
+	}
+	switch {
+	case err == nil:
+	case isBecomeErr(err):
+		result.Err = becomeErr(err, become, target)
+	case errors.Is(err, easyssh.ErrTimeout):
+		result.Err = xerrors.Wrap(err, xerrors.TimeoutError, "execute_command", task.Cmd, "command timed out")
+	case res.Signal != "":
+		result.Err = xerrors.Wrap(err, xerrors.ExitStatusError, "execute_command", task.Cmd,
+			fmt.Sprintf("command killed by signal %s", res.Signal))
+	case res.ExitCode != nil:
+		result.Err = xerrors.Wrap(err, xerrors.ExitStatusError, "execute_command", task.Cmd,
+			fmt.Sprintf("command exited with status %d", *res.ExitCode))
+	default:
+		result.Err = xerrors.Wrap(err, xerrors.ExecutionError, "execute_command", task.Cmd, "command execution failed")
+	}
+	return result
 }
 
-func upload(client *ssh.Client, timeout int, task config.Task, retry int, transferPolicy config.FileTransferPolicy) *model.TaskResult {
+func upload(conn *pool.Conn, host *config.Host, timeout int, task config.Task, retry int, transferPolicy config.FileTransferPolicy) *model.TaskResult {
 	var (
 		result model.TaskResult
-		local  string
-		remote string
+		local  = task.Local
+		remote = task.Remote
 		err    error
 	)
-	if utils.ContainsTemplate(result.Local) {
-		local, err = utils.RenderPathTemplate(task.Local, strings.Split(client.LocalAddr().String(), ":")[0])
+	if utils.ContainsTemplate(task.Local) {
+		local, err = utils.RenderTemplate(task.Local, host.TemplateData())
 		if err != nil {
 			result = model.TaskResult{
-				Task:   task,
-				StdErr: err,
+				Task: task,
+				Err:  err,
 			}
 			return &result
 		}
 	}
-	if utils.ContainsTemplate(result.Remote) {
-		remote, err = utils.RenderPathTemplate(task.Remote, strings.Split(client.LocalAddr().String(), ":")[0])
+	if utils.ContainsTemplate(task.Remote) {
+		remote, err = utils.RenderTemplate(task.Remote, host.TemplateData())
 		if err != nil {
 			result = model.TaskResult{
-				Task:   task,
-				StdErr: err,
+				Task: task,
+				Err:  err,
 			}
 			return &result
 		}
 	}
-	t, err := transfer.NewTransferHandler(remote, local, transferPolicy, client)
+	sftpClient, err := conn.SFTP()
 	if err != nil {
 		return &model.TaskResult{
 			Task: task,
-			StdErr: xerrors.Wrap(err, xerrors.ResourceError,
+			Err:  xerrors.ConnectionErr("open_sftp", host.DisplayName(), err),
+		}
+	}
+	t, err := transfer.NewTransferHandler(remote, local, transferPolicy, sftpClient, host.DisplayName())
+	if err != nil {
+		return &model.TaskResult{
+			Task: task,
+			Err: xerrors.Wrap(err, xerrors.ResourceError,
 				"init_transfer",
 				fmt.Sprintf("%s->%s", local, remote),
 				"failed to initialize file transfer"),
@@ -344,43 +410,50 @@ func upload(client *ssh.Client, timeout int, task config.Task, retry int, transf
 	}
 
 	return &model.TaskResult{
-		Task:   task,
-		StdErr: lastErr,
+		Task: task,
+		Err:  lastErr,
 	}
 }
 
-func download(client *ssh.Client, timeout int, task config.Task, retry int, transferPolicy config.FileTransferPolicy) *model.TaskResult {
+func download(conn *pool.Conn, host *config.Host, timeout int, task config.Task, retry int, transferPolicy config.FileTransferPolicy) *model.TaskResult {
 	var (
 		result model.TaskResult
-		local  string
-		remote string
+		local  = task.Local
+		remote = task.Remote
 		err    error
 	)
-	if utils.ContainsTemplate(result.Local) {
-		local, err = utils.RenderPathTemplate(task.Local, strings.Split(client.LocalAddr().String(), ":")[0])
+	if utils.ContainsTemplate(task.Local) {
+		local, err = utils.RenderTemplate(task.Local, host.TemplateData())
 		if err != nil {
 			result = model.TaskResult{
-				Task:   task,
-				StdErr: err,
+				Task: task,
+				Err:  err,
 			}
 			return &result
 		}
 	}
-	if utils.ContainsTemplate(result.Remote) {
-		remote, err = utils.RenderPathTemplate(task.Remote, strings.Split(client.LocalAddr().String(), ":")[0])
+	if utils.ContainsTemplate(task.Remote) {
+		remote, err = utils.RenderTemplate(task.Remote, host.TemplateData())
 		if err != nil {
 			result = model.TaskResult{
-				Task:   task,
-				StdErr: err,
+				Task: task,
+				Err:  err,
 			}
 			return &result
 		}
 	}
-	t, err := transfer.NewTransferHandler(remote, local, transferPolicy, client)
+	sftpClient, err := conn.SFTP()
 	if err != nil {
 		return &model.TaskResult{
 			Task: task,
-			StdErr: xerrors.Wrap(err, xerrors.ResourceError,
+			Err:  xerrors.ConnectionErr("open_sftp", host.DisplayName(), err),
+		}
+	}
+	t, err := transfer.NewTransferHandler(remote, local, transferPolicy, sftpClient, host.DisplayName())
+	if err != nil {
+		return &model.TaskResult{
+			Task: task,
+			Err: xerrors.Wrap(err, xerrors.ResourceError,
 				"init_transfer",
 				fmt.Sprintf("%s->%s", remote, local),
 				"failed to initialize file transfer"),
@@ -406,8 +479,8 @@ func download(client *ssh.Client, timeout int, task config.Task, retry int, tran
 	}
 
 	return &model.TaskResult{
-		Task:   task,
-		StdErr: lastErr,
+		Task: task,
+		Err:  lastErr,
 	}
 }
 
@@ -417,3 +490,26 @@ func shouldSkipTask(cfg *config.GossConfig, canProceed bool) bool {
 	}
 	return false
 }
+
+func isBecomeErr(err error) bool {
+	return errors.Is(err, easyssh.ErrBecomePassword) ||
+		errors.Is(err, easyssh.ErrBecomePasswordRequired) ||
+		errors.Is(err, easyssh.ErrPromptTimeout)
+}
+
+// becomeErr 提权密码错误或缺失归类为权限错误，无法识别的提示归类为超时，与命令执行失败区分
+func becomeErr(err error, become easyssh.Become, target string) error {
+	method, user := become.Method, become.User
+	if method == "" {
+		method = easyssh.DefaultBecomeMethod
+	}
+	if user == "" {
+		user = easyssh.DefaultBecomeUser
+	}
+	if errors.Is(err, easyssh.ErrPromptTimeout) {
+		return xerrors.Wrap(err, xerrors.TimeoutError, "become", target,
+			fmt.Sprintf("%s is waiting for input at an unrecognized prompt", method))
+	}
+	return xerrors.Wrap(err, xerrors.PermissionError, "become", target,
+		fmt.Sprintf("failed to become %s via %s", user, method))
+}
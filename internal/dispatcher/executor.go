@@ -0,0 +1,182 @@
+package dispatcher
+
+import (
+	"goss/internal/config"
+	"goss/internal/control"
+	"goss/internal/model"
+	"goss/internal/pool"
+	"goss/internal/utils"
+	"goss/internal/xerrors"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// executor 负责建立主机连接并执行任务
+// 本地模式使用进程内连接池，存在goss agent时通过控制套接字复用后台进程的连接
+type executor interface {
+	// connect 建立或复用主机连接，返回认证方式与协商的算法
+	connect(host *config.Host) (*model.ConnInfo, error)
+	// run 在主机上执行单个任务
+	run(host *config.Host, task *config.Task) *model.TaskResult
+	close()
+}
+
+// newExecutor 检测goss agent是否可用并选择执行方式
+func newExecutor(cfg *config.GossConfig) executor {
+	path := cfg.Connection.ControlPath
+	if path != "" && control.Ping(path) == nil {
+		slog.Info("Using goss agent connections", "socket", control.SocketPath(path))
+		cwd, _ := os.Getwd()
+		return &agentExecutor{path: path, cfg: cfg, cwd: cwd}
+	}
+	return &poolExecutor{pool: pool.New(cfg), cfg: cfg}
+}
+
+type poolExecutor struct {
+	pool *pool.Pool
+	cfg  *config.GossConfig
+}
+
+func (e *poolExecutor) connect(host *config.Host) (*model.ConnInfo, error) {
+	conn, err := e.pool.Get(host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	client, err := conn.SSH()
+	if err != nil {
+		return nil, err
+	}
+	algorithms := client.Algorithms()
+	return &model.ConnInfo{
+		AuthMethod:  client.AuthMethod,
+		KeyExchange: algorithms.KeyExchange,
+		HostKey:     algorithms.HostKey,
+		Cipher:      algorithms.Cipher,
+		MAC:         algorithms.MAC,
+	}, nil
+}
+
+func (e *poolExecutor) run(host *config.Host, task *config.Task) *model.TaskResult {
+	return ExecuteTask(e.pool, host, task, e.cfg)
+}
+
+func (e *poolExecutor) close() {
+	e.pool.Close()
+}
+
+// ExecuteTask 从连接池获取连接并执行单个任务，连接断开时自动重连
+func ExecuteTask(p *pool.Pool, host *config.Host, task *config.Task, cfg *config.GossConfig) *model.TaskResult {
+	conn, err := p.Get(host)
+	if err != nil {
+		return &model.TaskResult{
+			Task: *task,
+			Err:  xerrors.ConnectionErr("ssh_reconnect", host.DisplayName(), err),
+		}
+	}
+	defer conn.Release()
+	// 按主机变量渲染命令
+	if task.Template {
+		cmd, err := utils.RenderTemplate(task.Cmd, host.TemplateData())
+		if err != nil {
+			msg := "failed to render task cmd"
+			if host.Facts == nil && strings.Contains(task.Cmd, ".Facts") {
+				msg += ", facts were not gathered: set facts.gather or add a facts task before it"
+			}
+			return &model.TaskResult{
+				Task: *task,
+				Err:  xerrors.Wrap(err, xerrors.ValidationError, "render_template", host.DisplayName(), msg),
+			}
+		}
+		rendered := *task
+		rendered.Cmd = cmd
+		task = &rendered
+	}
+	var result *model.TaskResult
+	switch task.Type {
+	case config.CMD:
+		result = command(conn, cfg.Execution.TaskTimeout, becomeFor(host, task, cfg.Execution), *task)
+	case config.SCRIPT:
+		result = script(conn, host.DisplayName(), cfg.Execution.TaskTimeout, becomeFor(host, task, cfg.Execution), *task)
+	case config.UPLOAD:
+		result = upload(conn, host, cfg.FileTransfer.TransferTimeout, *task, cfg.FileTransfer.Retries, cfg.FileTransfer.OverwritePolicy)
+	case config.DOWNLOAD:
+		result = download(conn, host, cfg.FileTransfer.TransferTimeout, *task, cfg.FileTransfer.Retries, cfg.FileTransfer.OverwritePolicy)
+	default:
+		result = &model.TaskResult{
+			Task: *task,
+			Err:  xerrors.New(xerrors.ValidationError, "execute_task", task.Description, "unknown task type"),
+		}
+	}
+	return result
+}
+
+// becomeFor 任务的提权参数，任务中的设置优先于主机清单
+func becomeFor(host *config.Host, task *config.Task, execution *config.ExecutionConfig) easyssh.Become {
+	become := easyssh.Become{
+		Method:        easyssh.BecomeMethod(host.BecomeMethod),
+		User:          host.BecomeUser,
+		Password:      host.SudoPass,
+		Prompts:       execution.Become.Prompts,
+		Failures:      execution.Become.Failures,
+		PromptTimeout: time.Duration(execution.Become.PromptTimeout) * time.Second,
+	}
+	if task.BecomeMethod != "" {
+		become.Method = easyssh.BecomeMethod(task.BecomeMethod)
+	}
+	if task.BecomeUser != "" {
+		become.User = task.BecomeUser
+	}
+	return become
+}
+
+type agentExecutor struct {
+	path string
+	cfg  *config.GossConfig
+	cwd  string
+}
+
+func (e *agentExecutor) connect(host *config.Host) (*model.ConnInfo, error) {
+	resp, err := control.Call(e.path, &control.Request{Op: control.OpConnect, Host: host})
+	if err != nil {
+		// 还原后台进程返回的错误分类
+		if resp != nil && resp.ErrorType != "" {
+			return nil, xerrors.Wrap(err, xerrors.ErrorType(resp.ErrorType), "ssh_connect", host.DisplayName(), "goss agent connection failed")
+		}
+		return nil, err
+	}
+	if resp.Conn == nil {
+		return &model.ConnInfo{}, nil
+	}
+	return resp.Conn, nil
+}
+
+func (e *agentExecutor) run(host *config.Host, task *config.Task) *model.TaskResult {
+	resp, err := control.Call(e.path, &control.Request{
+		Op:           control.OpRun,
+		Host:         host,
+		Task:         task,
+		Execution:    e.cfg.Execution,
+		FileTransfer: e.cfg.FileTransfer,
+		Cwd:          e.cwd,
+	})
+	if err != nil {
+		return &model.TaskResult{
+			Task: *task,
+			Err:  xerrors.Wrap(err, xerrors.ResourceError, "agent_run", host.DisplayName(), "goss agent request failed"),
+		}
+	}
+	return &model.TaskResult{
+		Task:     *task,
+		Err:      xerrors.FromReport(resp.TaskErr),
+		StdOut:   resp.StdOut,
+		StdErr:   resp.StdErr,
+		ExitCode: resp.ExitCode,
+		Signal:   resp.Signal,
+	}
+}
+
+func (e *agentExecutor) close() {}
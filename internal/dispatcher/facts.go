@@ -0,0 +1,111 @@
+package dispatcher
+
+import (
+	"goss/internal/config"
+	"goss/internal/facts"
+	"goss/internal/model"
+	"goss/internal/xerrors"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GatherFacts 并发采集主机信息，refresh为true时忽略缓存
+func GatherFacts(hosts []*config.Host, cfg *config.GossConfig, refresh bool) []*model.HostFacts {
+	startTime := time.Now()
+	slog.Info("Gathering facts started", "Total number of hosts", len(hosts), "Maximum concurrency", cfg.Execution.MaxWorkers)
+	exec := newExecutor(cfg)
+	defer exec.close()
+	cache := newFactsCache(cfg)
+	maxWorkersCh := make(chan struct{}, cfg.Execution.MaxWorkers)
+	results := make([]*model.HostFacts, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		maxWorkersCh <- struct{}{}
+		go func(host *config.Host, i int) {
+			defer func() {
+				<-maxWorkersCh
+				wg.Done()
+			}()
+			result := &model.HostFacts{Index: i, HostIP: host.DisplayName()}
+			results[i] = result
+			f, err := gatherHostFacts(exec, host, cache, refresh)
+			if err != nil {
+				slog.Error("Failed to gather facts", "Host", host.DisplayName(), "error", err)
+				result.Error = err.Error()
+				return
+			}
+			result.Facts = f
+		}(host, i)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	slog.Info("Gathering facts completed", "Total time consumed", time.Since(startTime).Round(time.Millisecond))
+	return results
+}
+
+// gatherHostFacts 建立连接后采集主机信息
+func gatherHostFacts(exec executor, host *config.Host, cache *facts.Cache, refresh bool) (*facts.Facts, error) {
+	if !refresh {
+		if f, ok := cache.Load(factsKey(host)); ok {
+			host.Facts = f
+			return f, nil
+		}
+	}
+	if _, err := exec.connect(host); err != nil {
+		if _, ok := err.(*xerrors.GossError); !ok {
+			err = xerrors.ClassifyConnErr("ssh_connect", host.DisplayName(), err)
+		}
+		return nil, err
+	}
+	return gatherFacts(exec, host, cache, true)
+}
+
+// gatherFacts 优先使用未过期的缓存，否则在主机上执行采集脚本，结果保存到host.Facts与缓存
+func gatherFacts(exec executor, host *config.Host, cache *facts.Cache, refresh bool) (*facts.Facts, error) {
+	if !refresh {
+		if f, ok := cache.Load(factsKey(host)); ok {
+			host.Facts = f
+			return f, nil
+		}
+	}
+	result := exec.run(host, &config.Task{
+		Type:        config.CMD,
+		Description: "Gathering facts",
+		Cmd:         facts.Command(),
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	f, err := facts.Parse(result.StdOut)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.ExecutionError, "gather_facts", host.DisplayName(), "failed to parse facts")
+	}
+	host.Facts = f
+	if err := cache.Save(factsKey(host), f); err != nil {
+		slog.Warn("Failed to write facts cache", "Host", host.DisplayName(), "ERROR", err.Error())
+	}
+	return f, nil
+}
+
+// factsTask 执行facts任务，总是重新采集
+func factsTask(exec executor, host *config.Host, task *config.Task, cache *facts.Cache) *model.TaskResult {
+	f, err := gatherFacts(exec, host, cache, true)
+	if err != nil {
+		return &model.TaskResult{Task: *task, Err: err}
+	}
+	return &model.TaskResult{Task: *task, StdOut: f.Summary()}
+}
+
+func newFactsCache(cfg *config.GossConfig) *facts.Cache {
+	return facts.NewCache(easyssh.ExpandHome(cfg.Facts.CacheDir), time.Duration(cfg.Facts.CacheTTL)*time.Second)
+}
+
+// factsKey 缓存键，同一主机名称与连接地址共用缓存
+func factsKey(host *config.Host) string {
+	return host.DisplayName() + "|" + net.JoinHostPort(host.IP, host.Port)
+}
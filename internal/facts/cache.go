@@ -0,0 +1,65 @@
+package facts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache 按主机缓存采集结果，每个主机一个JSON文件
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewCache 创建缓存，ttl<=0 表示不使用缓存
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+// Load 读取未过期的缓存
+func (c *Cache) Load(key string) (*Facts, bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, false
+	}
+	content, err := os.ReadFile(c.file(key))
+	if err != nil {
+		return nil, false
+	}
+	var f Facts
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, false
+	}
+	if time.Since(f.GatheredAt) > c.ttl {
+		return nil, false
+	}
+	return &f, true
+}
+
+// Save 写入缓存，目录不存在时创建
+func (c *Cache) Save(key string, f *Facts) error {
+	if c == nil || c.ttl <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	content, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp := c.file(key) + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.file(key))
+}
+
+// file 缓存文件名取主机标识的哈希，避免主机名中的特殊字符
+func (c *Cache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
@@ -0,0 +1,321 @@
+// Package facts 采集并解析主机的系统信息(操作系统、内核、CPU、内存、磁盘、网卡、init系统与包管理器)
+package facts
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Facts 主机信息，模板中通过 {{ .Facts.OS.Family }}、{{ .Facts.CPUCount }} 等引用
+type Facts struct {
+	Hostname       string      `json:"hostname"`
+	OS             OS          `json:"os"`
+	Kernel         Kernel      `json:"kernel"`
+	CPUCount       int         `json:"cpu_count"`
+	Memory         Memory      `json:"memory"`
+	Disks          []Disk      `json:"disks"`
+	Interfaces     []Interface `json:"interfaces"`
+	InitSystem     string      `json:"init_system"`
+	PackageManager string      `json:"package_manager"`
+	// 采集时间，用于判断缓存是否过期
+	GatheredAt time.Time `json:"gathered_at"`
+}
+
+// OS 操作系统发行版信息，取自 /etc/os-release，macOS取自 sw_vers
+type OS struct {
+	// 发行版家族，与ansible的os_family一致：Debian、RedHat、Suse、Alpine、Archlinux、Gentoo、Darwin
+	Family     string `json:"family"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	VersionID  string `json:"version_id"`
+	PrettyName string `json:"pretty_name"`
+}
+
+// Kernel uname 输出的内核信息
+type Kernel struct {
+	Name    string `json:"name"`
+	Release string `json:"release"`
+	Arch    string `json:"arch"`
+}
+
+// Memory 内存信息(MB)
+type Memory struct {
+	TotalMB     int64 `json:"total_mb"`
+	AvailableMB int64 `json:"available_mb"`
+}
+
+// Disk 已挂载的本地文件系统(MB)
+type Disk struct {
+	Device      string `json:"device"`
+	Mount       string `json:"mount"`
+	FSType      string `json:"fstype"`
+	SizeMB      int64  `json:"size_mb"`
+	UsedMB      int64  `json:"used_mb"`
+	AvailableMB int64  `json:"available_mb"`
+}
+
+// Interface 网卡及其地址，地址带前缀长度，例如 10.0.0.5/24
+type Interface struct {
+	Name string   `json:"name"`
+	MAC  string   `json:"mac"`
+	IPv4 []string `json:"ipv4"`
+	IPv6 []string `json:"ipv6"`
+}
+
+// 采集脚本的各段输出以 @@名称 开头，所有命令的错误输出都被丢弃，缺少的命令只会导致对应字段为空
+const script = `echo @@hostname; hostname 2>/dev/null || uname -n
+echo @@os-release; cat /etc/os-release 2>/dev/null
+echo @@sw_vers; sw_vers 2>/dev/null
+echo @@uname; uname -s; uname -r; uname -m
+echo @@cpu; getconf _NPROCESSORS_ONLN 2>/dev/null || nproc 2>/dev/null || sysctl -n hw.ncpu 2>/dev/null
+echo @@meminfo; cat /proc/meminfo 2>/dev/null || sysctl hw.memsize 2>/dev/null
+echo @@df; df -P -k 2>/dev/null
+echo @@mounts; cat /proc/mounts 2>/dev/null
+echo @@ip; ip -o addr show 2>/dev/null
+echo @@mac; for d in /sys/class/net/*; do [ -r "$d/address" ] && echo "${d##*/} $(cat "$d/address")"; done 2>/dev/null
+echo @@init; if [ -d /run/systemd/system ]; then echo systemd; elif [ -x /sbin/openrc ] || [ -x /sbin/openrc-run ]; then echo openrc; else cat /proc/1/comm 2>/dev/null || ps -p 1 -o comm= 2>/dev/null; fi
+echo @@pkg; for p in apt-get dnf yum zypper apk pacman emerge brew pkg; do if command -v $p >/dev/null 2>&1; then echo $p; break; fi; done
+exit 0`
+
+// Command 返回在远端执行的采集命令，通过sh执行以免受登录shell(例如fish)影响
+func Command() string {
+	return "sh -c '" + script + "'"
+}
+
+// 发行版ID到家族的映射，未列出的发行版按ID_LIKE查找
+var osFamilies = map[string]string{
+	"debian": "Debian", "ubuntu": "Debian", "linuxmint": "Debian", "raspbian": "Debian", "kali": "Debian", "pop": "Debian", "uos": "Debian", "deepin": "Debian",
+	"rhel": "RedHat", "centos": "RedHat", "fedora": "RedHat", "rocky": "RedHat", "almalinux": "RedHat", "ol": "RedHat", "amzn": "RedHat",
+	"scientific": "RedHat", "openeuler": "RedHat", "anolis": "RedHat", "kylin": "RedHat", "tencentos": "RedHat", "alinux": "RedHat",
+	"sles": "Suse", "sled": "Suse", "opensuse": "Suse", "opensuse-leap": "Suse", "opensuse-tumbleweed": "Suse", "suse": "Suse",
+	"alpine": "Alpine", "arch": "Archlinux", "manjaro": "Archlinux", "gentoo": "Gentoo",
+}
+
+// 不计入磁盘信息的文件系统类型
+var ignoredFSTypes = map[string]bool{"squashfs": true, "overlay": true, "tmpfs": true, "devtmpfs": true}
+
+// Parse 解析采集脚本的输出
+func Parse(output string) (*Facts, error) {
+	sections := splitSections(output)
+	if _, ok := sections["uname"]; !ok {
+		return nil, fmt.Errorf("unexpected facts output: %s", firstLine(output))
+	}
+	f := &Facts{GatheredAt: time.Now()}
+	if lines := sections["hostname"]; len(lines) > 0 {
+		f.Hostname = lines[0]
+	}
+	if lines := sections["uname"]; len(lines) >= 3 {
+		f.Kernel = Kernel{Name: lines[0], Release: lines[1], Arch: lines[2]}
+	}
+	f.OS = parseOSRelease(sections["os-release"])
+	if f.OS.ID == "" {
+		f.OS = parseSwVers(sections["sw_vers"])
+	}
+	if f.OS.Family == "" {
+		f.OS.Family = f.Kernel.Name
+	}
+	if lines := sections["cpu"]; len(lines) > 0 {
+		f.CPUCount, _ = strconv.Atoi(lines[0])
+	}
+	f.Memory = parseMemory(sections["meminfo"])
+	f.Disks = parseDisks(sections["df"], sections["mounts"])
+	f.Interfaces = parseInterfaces(sections["ip"], sections["mac"])
+	if lines := sections["init"]; len(lines) > 0 {
+		f.InitSystem = path.Base(lines[0])
+		if f.InitSystem == "init" {
+			f.InitSystem = "sysvinit"
+		}
+	}
+	if lines := sections["pkg"]; len(lines) > 0 {
+		f.PackageManager = strings.TrimSuffix(lines[0], "-get")
+	}
+	return f, nil
+}
+
+// Summary 单行描述，用于任务输出与表格
+func (f *Facts) Summary() string {
+	name := f.OS.PrettyName
+	if name == "" {
+		name = strings.TrimSpace(f.OS.Name + " " + f.OS.Version)
+	}
+	return fmt.Sprintf("%s (%s), %s %s %s, %d CPU, %d MB memory", name, f.OS.Family,
+		f.Kernel.Name, f.Kernel.Release, f.Kernel.Arch, f.CPUCount, f.Memory.TotalMB)
+}
+
+func splitSections(output string) map[string][]string {
+	sections := make(map[string][]string)
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if name, ok := strings.CutPrefix(line, "@@"); ok {
+			current = name
+			sections[current] = []string{}
+			continue
+		}
+		if current != "" && strings.TrimSpace(line) != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+	return sections
+}
+
+func parseOSRelease(lines []string) OS {
+	values := make(map[string]string)
+	for _, line := range lines {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[k] = strings.Trim(v, `"'`)
+	}
+	info := OS{
+		ID:         values["ID"],
+		Name:       values["NAME"],
+		Version:    values["VERSION"],
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}
+	info.Family = osFamilies[info.ID]
+	if info.Family == "" {
+		for _, like := range strings.Fields(values["ID_LIKE"]) {
+			if family := osFamilies[like]; family != "" {
+				info.Family = family
+				break
+			}
+		}
+	}
+	return info
+}
+
+func parseSwVers(lines []string) OS {
+	values := make(map[string]string)
+	for _, line := range lines {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if values["ProductName"] == "" {
+		return OS{}
+	}
+	return OS{
+		Family:     "Darwin",
+		ID:         "macos",
+		Name:       values["ProductName"],
+		Version:    values["ProductVersion"],
+		VersionID:  values["ProductVersion"],
+		PrettyName: values["ProductName"] + " " + values["ProductVersion"],
+	}
+}
+
+// parseMemory 解析 /proc/meminfo(kB)或macOS的 hw.memsize(字节)
+func parseMemory(lines []string) Memory {
+	var m Memory
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			m.TotalMB = n / 1024
+		case "MemAvailable:":
+			m.AvailableMB = n / 1024
+		case "hw.memsize:":
+			m.TotalMB = n / 1024 / 1024
+		}
+	}
+	return m
+}
+
+// parseDisks 解析 df -P -k 输出，只保留设备路径以/开头的本地文件系统
+func parseDisks(df, mounts []string) []Disk {
+	fsTypes := make(map[string]string)
+	for _, line := range mounts {
+		if fields := strings.Fields(line); len(fields) >= 3 {
+			fsTypes[fields[1]] = fields[2]
+		}
+	}
+	var disks []Disk
+	for i, line := range df {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) < 6 || !strings.HasPrefix(fields[0], "/") {
+			continue
+		}
+		mount := strings.Join(fields[5:], " ")
+		if ignoredFSTypes[fsTypes[mount]] {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		used, _ := strconv.ParseInt(fields[2], 10, 64)
+		avail, _ := strconv.ParseInt(fields[3], 10, 64)
+		disks = append(disks, Disk{
+			Device:      fields[0],
+			Mount:       mount,
+			FSType:      fsTypes[mount],
+			SizeMB:      size / 1024,
+			UsedMB:      used / 1024,
+			AvailableMB: avail / 1024,
+		})
+	}
+	return disks
+}
+
+// parseInterfaces 解析 ip -o addr show 输出，MAC地址取自 /sys/class/net
+func parseInterfaces(addrs, macs []string) []Interface {
+	var ifaces []*Interface
+	byName := make(map[string]*Interface)
+	get := func(name string) *Interface {
+		if iface, ok := byName[name]; ok {
+			return iface
+		}
+		iface := &Interface{Name: name}
+		byName[name] = iface
+		ifaces = append(ifaces, iface)
+		return iface
+	}
+	for _, line := range addrs {
+		// 2: eth0    inet 10.0.0.5/24 brd 10.0.0.255 scope global eth0\       valid_lft forever ...
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		// veth等网卡名称带有 @ifN 后缀
+		name, _, _ := strings.Cut(strings.TrimSuffix(fields[1], ":"), "@")
+		iface := get(name)
+		switch fields[2] {
+		case "inet":
+			iface.IPv4 = append(iface.IPv4, fields[3])
+		case "inet6":
+			iface.IPv6 = append(iface.IPv6, fields[3])
+		}
+	}
+	for _, line := range macs {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			get(fields[0]).MAC = fields[1]
+		}
+	}
+	result := make([]Interface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		result = append(result, *iface)
+	}
+	return result
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if idx := strings.Index(s, "\n"); idx != -1 {
+		return s[:idx]
+	}
+	return s
+}
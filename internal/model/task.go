@@ -1,15 +1,63 @@
 package model
 
-import "goss/internal/config"
+import (
+	"fmt"
+	"goss/internal/config"
+	"goss/internal/facts"
+	"time"
+)
 
 type HostTask struct {
 	Index   int           // 主机的索引，由于主机执行顺序是并发执行通过这个index在输出时进行排序
 	HostIP  string        // 记录主机的ip信息
+	Conn    *ConnInfo     // 连接信息，连接失败时为空
 	Results []*TaskResult // 记录主机任务执行情况
 }
 
+// ConnInfo 主机连接的认证方式与协商得到的算法
+type ConnInfo struct {
+	AuthMethod  string
+	KeyExchange string
+	HostKey     string
+	Cipher      string
+	MAC         string
+}
+
+// Summary 连接信息的单行描述
+func (c *ConnInfo) Summary() string {
+	mac := c.MAC
+	if mac == "" {
+		// AEAD加密算法不单独协商MAC
+		mac = "implicit"
+	}
+	return fmt.Sprintf("auth=%s kex=%s hostkey=%s cipher=%s mac=%s", c.AuthMethod, c.KeyExchange, c.HostKey, c.Cipher, mac)
+}
+
 type TaskResult struct {
 	config.Task        // 继承于config包的任务配置
-	StdErr      error  // 当前任务执行失败原因
-	StdOut      string // 当前任务执行成功的信息
+	Err         error  // 当前任务执行失败原因，命令以非零状态退出时类型为 xerrors.ExitStatusError
+	StdOut      string // 命令的标准输出，文件传输任务为传输信息
+	StdErr      string // 命令的标准错误，提权执行时合并在StdOut中
+	// 命令退出码，任务未执行、文件传输任务或没有收到退出状态时为nil
+	ExitCode *int
+	// 命令被信号终止时的信号名
+	Signal    string
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+}
+
+// HostFacts 单个主机的信息采集结果
+type HostFacts struct {
+	Index  int          `json:"index"`
+	HostIP string       `json:"host"`
+	Facts  *facts.Facts `json:"facts,omitempty"`
+	Error  string       `json:"error,omitempty"` // 连接或采集失败原因
+}
+
+// SetTiming 记录任务的开始、结束时间与耗时
+func (r *TaskResult) SetTiming(start, end time.Time) {
+	r.StartTime = start
+	r.EndTime = end
+	r.Duration = end.Sub(start)
 }
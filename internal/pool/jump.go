@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"goss/internal/config"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+// JumpFor 返回主机需要经过的最后一跳跳板机连接，无需跳板时返回nil
+// 同一跳板机后的所有主机共享一个ssh连接
+func (p *Pool) JumpFor(host *config.Host) (*easyssh.Client, error) {
+	name := p.cfg.HostJump(host)
+	if name == "" {
+		return nil, nil
+	}
+	return p.jump(name)
+}
+
+// jump 获取跳板机连接，首次获取或连接断开时按链路逐级建立连接
+func (p *Pool) jump(name string) (*easyssh.Client, error) {
+	jump, ok := p.cfg.LookupJump(name)
+	if !ok {
+		return nil, fmt.Errorf("jump host %s is not defined in jump_hosts", name)
+	}
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("connection pool is closed")
+		}
+		c, ok := p.jumps[name]
+		if !ok {
+			c = &Conn{
+				key: "jump/" + name,
+				dial: func() (*easyssh.Client, error) {
+					var via *easyssh.Client
+					if jump.Jump != "" {
+						v, err := p.jump(jump.Jump)
+						if err != nil {
+							return nil, err
+						}
+						via = v
+					}
+					client, err := p.dialWithRetry("jump/"+name, func() (*easyssh.Client, error) {
+						return easyssh.NewClient(p.jumpOpts(jump, via))
+					})
+					if err != nil {
+						return nil, err
+					}
+					slog.Info("Jump host connection established",
+						"JumpHost", name,
+						"Address", jump.Address,
+						"AuthMethod", client.AuthMethod)
+					return client, nil
+				},
+			}
+			p.jumps[name] = c
+		}
+		p.mu.Unlock()
+
+		err := c.acquire()
+		if errors.Is(err, errConnReaped) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 跳板机连接不参与闲置回收，引用计数只用于保持一致
+		client, err := c.SSH()
+		c.Release()
+		return client, err
+	}
+}
+
+func (p *Pool) jumpOpts(jump *config.JumpHost, via *easyssh.Client) easyssh.Opts {
+	addr, port := jump.Address, strconv.Itoa(jump.Port)
+	if h, pt, err := net.SplitHostPort(jump.Address); err == nil {
+		addr, port = h, pt
+	}
+	var identityFiles []string
+	if jump.IdentityFile != "" {
+		identityFiles = []string{jump.IdentityFile}
+	} else {
+		identityFiles = p.cfg.Connection.IdentityFiles
+	}
+	algorithms := p.cfg.HostAlgorithms(config.AlgorithmPolicy{}, addr)
+	return easyssh.Opts{
+		IP:                addr,
+		Port:              port,
+		User:              jump.User,
+		Passwd:            jump.Password,
+		IdentityFiles:     identityFiles,
+		Passphrase:        jump.Passphrase,
+		CertificateFile:   jump.CertificateFile,
+		AgentSocket:       p.agentSocket(),
+		ConnectTimeout:    p.cfg.Connection.ConnectTimeout,
+		Mode:              easyssh.SecurityMode(p.cfg.Connection.SecurityMode),
+		PromptTimeout:     time.Duration(p.cfg.Connection.HostKeyPromptTimeout) * time.Second,
+		KnownHostsFile:    p.cfg.Connection.KnownHostsFile,
+		Via:               via,
+		Ciphers:           algorithms.Ciphers,
+		KeyExchanges:      algorithms.KeyExchanges,
+		MACs:              algorithms.MACs,
+		HostKeyAlgorithms: algorithms.HostKeyAlgorithms,
+	}
+}
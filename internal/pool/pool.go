@@ -0,0 +1,406 @@
+package pool
+
+/*
+ssh/sftp连接池
+每个主机只维护一个ssh连接和一个按需创建的sftp连接，
+后台定时发送keepalive探测连接状态并回收闲置连接，连接断开后在下次获取时自动重连
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goss/internal/config"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// keepalive请求等待响应的最长时间
+const keepaliveReplyTimeout = 10 * time.Second
+
+// 连接已被回收，需要重新从连接池获取
+var errConnReaped = errors.New("connection has been reaped")
+
+type Pool struct {
+	cfg   *config.GossConfig
+	mu    sync.Mutex
+	conns map[string]*Conn
+	// 跳板机连接，key为跳板机名称，不参与闲置回收
+	jumps  map[string]*Conn
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// Conn 池中的单个主机连接
+type Conn struct {
+	key  string
+	dial func() (*easyssh.Client, error)
+
+	mu       sync.Mutex
+	client   *easyssh.Client
+	sftp     *sftp.Client
+	refs     int
+	lastUsed time.Time
+	reaped   bool
+}
+
+// New 创建连接池并启动keepalive与闲置回收协程
+func New(cfg *config.GossConfig) *Pool {
+	p := &Pool{
+		cfg:   cfg,
+		conns: make(map[string]*Conn),
+		jumps: make(map[string]*Conn),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.maintain()
+	return p
+}
+
+// Get 获取主机连接，连接不存在或已断开时重新建立，使用完毕后需要调用Release
+func (p *Pool) Get(host *config.Host) (*Conn, error) {
+	key := p.hostKey(host)
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("connection pool is closed")
+		}
+		c, ok := p.conns[key]
+		if !ok {
+			c = &Conn{
+				key: key,
+				dial: func() (*easyssh.Client, error) {
+					// 跳板机连接已按自身配置重试
+					via, err := p.JumpFor(host)
+					if err != nil {
+						return nil, err
+					}
+					return p.dialWithRetry(host.DisplayName(), func() (*easyssh.Client, error) {
+						return easyssh.NewClient(p.hostOpts(host, via))
+					})
+				},
+			}
+			p.conns[key] = c
+		}
+		p.mu.Unlock()
+
+		err := c.acquire()
+		if errors.Is(err, errConnReaped) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+// Close 关闭池中所有连接，先关闭主机连接再关闭跳板机连接
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	conns := make([]*Conn, 0, len(p.conns)+len(p.jumps))
+	for _, c := range p.conns {
+		conns = append(conns, c)
+	}
+	for _, c := range p.jumps {
+		conns = append(conns, c)
+	}
+	p.mu.Unlock()
+
+	close(p.stop)
+	<-p.done
+	for _, c := range conns {
+		c.close()
+	}
+}
+
+// Len 返回池中主机连接数量
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// SSH 返回当前的ssh连接，连接在使用过程中断开时返回错误
+func (c *Conn) SSH() (*easyssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return nil, fmt.Errorf("ssh connection to %s is closed", c.key)
+	}
+	return c.client, nil
+}
+
+// SFTP 返回复用的sftp连接，首次调用时创建
+func (c *Conn) SFTP() (*sftp.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return nil, fmt.Errorf("ssh connection to %s is closed", c.key)
+	}
+	if c.sftp == nil {
+		s, err := sftp.NewClient(c.client.Client)
+		if err != nil {
+			return nil, err
+		}
+		c.sftp = s
+	}
+	return c.sftp, nil
+}
+
+// Release 归还连接，连接保留在池中等待复用或闲置回收
+func (c *Conn) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refs > 0 {
+		c.refs--
+	}
+	c.lastUsed = time.Now()
+}
+
+func (c *Conn) acquire() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reaped {
+		return errConnReaped
+	}
+	if c.client == nil {
+		client, err := c.dial()
+		if err != nil {
+			return err
+		}
+		c.client = client
+		// 连接断开时清理，下次获取时重连
+		go func() {
+			client.Wait()
+			c.markDead(client)
+		}()
+	}
+	c.refs++
+	c.lastUsed = time.Now()
+	return nil
+}
+
+func (c *Conn) markDead(client *easyssh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != client {
+		return
+	}
+	slog.Debug("Pooled connection closed", "conn", c.key)
+	if c.sftp != nil {
+		c.sftp.Close()
+		c.sftp = nil
+	}
+	c.client = nil
+}
+
+func (c *Conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reaped = true
+	if c.sftp != nil {
+		c.sftp.Close()
+		c.sftp = nil
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// maintain 定时发送keepalive并回收闲置连接
+func (p *Pool) maintain() {
+	defer close(p.done)
+	interval := time.Duration(p.cfg.Connection.KeepaliveInterval) * time.Second
+	if interval <= 0 {
+		// 关闭keepalive时仍需要定期检查闲置连接
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.reapIdle()
+			if p.cfg.Connection.KeepaliveInterval > 0 {
+				p.keepalive()
+			}
+		}
+	}
+}
+
+// reapIdle 回收闲置连接，不会同时持有连接池与单个连接的锁：
+// 获取连接时可能在持有连接锁的情况下经由 JumpFor 获取连接池锁
+func (p *Pool) reapIdle() {
+	idle := time.Duration(p.cfg.Connection.IdleTimeout) * time.Second
+	if idle <= 0 {
+		return
+	}
+	p.mu.Lock()
+	conns := make(map[string]*Conn, len(p.conns))
+	for key, c := range p.conns {
+		conns[key] = c
+	}
+	p.mu.Unlock()
+
+	for key, c := range conns {
+		client, sftpClient, ok := c.detachIdle(idle)
+		if !ok {
+			continue
+		}
+		slog.Debug("Reaping idle connection", "conn", key)
+		p.mu.Lock()
+		if p.conns[key] == c {
+			delete(p.conns, key)
+		}
+		p.mu.Unlock()
+		// 关闭可能阻塞在网络上，在锁外进行
+		if sftpClient != nil {
+			sftpClient.Close()
+		}
+		if client != nil {
+			client.Close()
+		}
+	}
+}
+
+// detachIdle 连接闲置超过idle时标记为已回收并取出底层连接，由调用方关闭。
+// 检查与标记在同一临界区内完成，标记后acquire不会再使用该连接
+func (c *Conn) detachIdle(idle time.Duration) (*easyssh.Client, *sftp.Client, bool) {
+	// 正在建立连接的主机跳过，避免阻塞回收
+	if !c.mu.TryLock() {
+		return nil, nil, false
+	}
+	defer c.mu.Unlock()
+	if c.reaped || c.refs > 0 || time.Since(c.lastUsed) <= idle {
+		return nil, nil, false
+	}
+	c.reaped = true
+	client, sftpClient := c.client, c.sftp
+	c.client, c.sftp = nil, nil
+	return client, sftpClient, true
+}
+
+func (p *Pool) keepalive() {
+	p.mu.Lock()
+	conns := make([]*Conn, 0, len(p.conns)+len(p.jumps))
+	for _, c := range p.conns {
+		conns = append(conns, c)
+	}
+	for _, c := range p.jumps {
+		conns = append(conns, c)
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c *Conn) {
+			defer wg.Done()
+			client, err := c.SSH()
+			if err != nil {
+				return
+			}
+			if err := sendKeepalive(client); err != nil {
+				// 关闭后由Wait协程清理，下次获取时重连
+				slog.Warn("Keepalive failed, connection will be re-established on next use", "conn", c.key, "error", err)
+				client.Close()
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+func sendKeepalive(client *easyssh.Client) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(keepaliveReplyTimeout):
+		return errors.New("keepalive timed out")
+	}
+}
+
+// hostKey 返回主机在池中的key，由跳板链路、实际连接地址、用户与认证信息组成。
+// 同一地址经不同跳板机到达的主机不共享连接，认证信息变化后不再复用旧连接
+func (p *Pool) hostKey(host *config.Host) string {
+	route := host.User + "@" + net.JoinHostPort(host.DialAddr(), host.Port)
+	if name := p.cfg.HostJump(host); name != "" {
+		chain, err := p.cfg.JumpChain(name)
+		if err != nil {
+			chain = []string{name}
+		}
+		route = strings.Join(chain, ">") + ">" + route
+	}
+	opts := p.hostOpts(host, nil)
+	h := sha256.New()
+	for _, v := range append([]string{opts.IP, opts.Passwd, opts.Passphrase, opts.CertificateFile, opts.AgentSocket}, opts.IdentityFiles...) {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	// 私钥文件被替换后重新建立连接
+	for _, path := range opts.IdentityFiles {
+		if info, err := os.Stat(easyssh.ExpandHome(path)); err == nil {
+			h.Write([]byte(info.ModTime().String()))
+		}
+	}
+	return route + "#" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// hostOpts 根据主机配置与全局配置构建连接参数
+func (p *Pool) hostOpts(host *config.Host, via *easyssh.Client) easyssh.Opts {
+	identityFiles := p.cfg.Connection.IdentityFiles
+	if host.IdentityFile != "" {
+		identityFiles = []string{host.IdentityFile}
+	}
+	algorithms := p.cfg.HostAlgorithms(host.Algorithms, host.DisplayName(), host.IP)
+	return easyssh.Opts{
+		IP:                host.IP,
+		DialAddr:          host.Addr,
+		Port:              host.Port,
+		User:              host.User,
+		Passwd:            host.Password,
+		IdentityFiles:     identityFiles,
+		Passphrase:        host.Passphrase,
+		CertificateFile:   host.CertificateFile,
+		AgentSocket:       p.agentSocket(),
+		ConnectTimeout:    host.Timeout(p.cfg.Connection),
+		Mode:              easyssh.SecurityMode(host.Security(p.cfg.Connection)),
+		PromptTimeout:     time.Duration(p.cfg.Connection.HostKeyPromptTimeout) * time.Second,
+		KnownHostsFile:    p.cfg.Connection.KnownHostsFile,
+		Via:               via,
+		Ciphers:           algorithms.Ciphers,
+		KeyExchanges:      algorithms.KeyExchanges,
+		MACs:              algorithms.MACs,
+		HostKeyAlgorithms: algorithms.HostKeyAlgorithms,
+	}
+}
+
+func (p *Pool) agentSocket() string {
+	if p.cfg.Connection.UseAgent {
+		return easyssh.AgentSocketFromEnv()
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"goss/internal/xerrors"
+	"goss/pkg/easyssh"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// dialWithRetry 按照connect_retries配置重试建立连接，重试间隔为带抖动的指数退避
+// 认证失败与主机密钥错误不会重试，返回的错误均为分类后的xerrors.GossError
+func (p *Pool) dialWithRetry(target string, dial func() (*easyssh.Client, error)) (*easyssh.Client, error) {
+	attempts := 1 + p.cfg.Connection.ConnectRetries
+	for i := 1; ; i++ {
+		client, err := dial()
+		if err == nil {
+			return client, nil
+		}
+		gerr := xerrors.ClassifyConnErr("ssh_connect", target, err).WithDetails(map[string]interface{}{
+			"attempts": i,
+		})
+		if i >= attempts || !xerrors.IsRetryable(gerr) {
+			return nil, gerr
+		}
+		delay := p.backoff(i)
+		slog.Warn("SSH connection failed, retrying",
+			"target", target,
+			"ErrorType", gerr.Type,
+			"attempt", i,
+			"next retry in", delay.Round(time.Millisecond),
+			"error", err)
+		time.Sleep(delay)
+	}
+}
+
+// backoff 计算第n次失败后的等待时间：base*2^(n-1)，不超过上限，并在[50%,100%]之间随机抖动
+func (p *Pool) backoff(n int) time.Duration {
+	base := time.Duration(p.cfg.Connection.RetryBackoff * float64(time.Second))
+	limit := time.Duration(p.cfg.Connection.RetryMaxBackoff * float64(time.Second))
+	delay := base
+	for i := 1; i < n && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
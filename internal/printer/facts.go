@@ -0,0 +1,138 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"goss/internal/facts"
+	"goss/internal/model"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// PrintFacts 输出主机信息，格式与任务结果相同：终端表格、json文件或excel文件
+func PrintFacts(results []*model.HostFacts, format Format) {
+	switch format {
+	case FormatJSON:
+		printFactsJSON(results)
+	case FormatExcel:
+		printFactsExcel(results)
+	default:
+		printFactsTable(results)
+	}
+}
+
+func printFactsTable(results []*model.HostFacts) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Host", "Hostname", "OS", "Family", "Kernel", "Arch", "CPUs", "Memory (MB)", "Init", "Package Manager", "Error"})
+	for _, r := range results {
+		if r.Facts == nil {
+			t.AppendRow(table.Row{r.HostIP, "-", "-", "-", "-", "-", "-", "-", "-", "-", r.Error})
+			continue
+		}
+		f := r.Facts
+		t.AppendRow(table.Row{r.HostIP, f.Hostname, osName(f), f.OS.Family, f.Kernel.Release, f.Kernel.Arch,
+			f.CPUCount, f.Memory.TotalMB, f.InitSystem, f.PackageManager, ""})
+	}
+	t.Render()
+}
+
+func printFactsJSON(results []*model.HostFacts) {
+	slog.Info("Starting to save JSON information...")
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Export json failed, err: %s\n", err)
+		return
+	}
+	fileP := path.Join(dir, "facts-"+time.Now().Format("2006-01-02T150405")+".json")
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		slog.Error("Failed to encode into JSON information.", slog.String("ERROR", err.Error()))
+		return
+	}
+	if err := os.WriteFile(fileP, content, 0644); err != nil {
+		slog.Error("Failed to save the output content as a JSON file.", slog.String("ERROR", err.Error()))
+		return
+	}
+	slog.Info("The JSON file has been successfully generated.", slog.String("PATH", fileP))
+}
+
+func printFactsExcel(results []*model.HostFacts) {
+	slog.Info("Starting to generate Excel document...")
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Export excel failed, err: %s\n", err)
+		return
+	}
+	fileP := path.Join(dir, "facts-"+time.Now().Format("2006-01-02T150405")+".xlsx")
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Sheet1"
+	headers := []string{"Host", "Hostname", "OS", "Family", "Kernel", "Arch", "CPUs", "Memory (MB)", "Available Memory (MB)",
+		"Disks", "Interfaces", "Init", "Package Manager", "Gathered At", "Error"}
+	for col, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+		f.SetCellValue(sheet, cell, header)
+	}
+	for i, r := range results {
+		row := []any{r.HostIP}
+		if hf := r.Facts; hf != nil {
+			row = append(row, hf.Hostname, osName(hf), hf.OS.Family, hf.Kernel.Release, hf.Kernel.Arch, hf.CPUCount,
+				hf.Memory.TotalMB, hf.Memory.AvailableMB, formatDisks(hf.Disks), formatInterfaces(hf.Interfaces),
+				hf.InitSystem, hf.PackageManager, hf.GatheredAt.Format(time.RFC3339), "")
+		} else {
+			row = append(row, "", "", "", "", "", "", "", "", "", "", "", "", "", r.Error)
+		}
+		for col, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(col+1, i+2)
+			f.SetCellValue(sheet, cell, value)
+		}
+	}
+	style, err := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "left", Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		slog.Warn("Failed to generate global table style", slog.String("Tips", err.Error()))
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(len(headers), len(results)+1)
+	if err := f.SetCellStyle(sheet, "A1", lastCell, style); err != nil {
+		slog.Warn("Failed to set overall table style", slog.String("Tips", err.Error()))
+	}
+	if err := f.SaveAs(fileP); err != nil {
+		slog.Error("Excel document generation failed.", slog.String("ERROR", err.Error()))
+	} else {
+		slog.Info("The Excel document has been successfully generated.", slog.String("PATH", fileP))
+	}
+}
+
+func osName(f *facts.Facts) string {
+	if f.OS.PrettyName != "" {
+		return f.OS.PrettyName
+	}
+	return strings.TrimSpace(f.OS.Name + " " + f.OS.Version)
+}
+
+// formatDisks 每个文件系统一行：挂载点 设备 类型 已用/总量
+func formatDisks(disks []facts.Disk) string {
+	lines := make([]string, 0, len(disks))
+	for _, d := range disks {
+		lines = append(lines, fmt.Sprintf("%s %s %s %d/%d MB", d.Mount, d.Device, d.FSType, d.UsedMB, d.SizeMB))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatInterfaces 每个网卡一行：名称 MAC 地址
+func formatInterfaces(ifaces []facts.Interface) string {
+	lines := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs := append(append([]string{}, iface.IPv4...), iface.IPv6...)
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s %s %s", iface.Name, iface.MAC, strings.Join(addrs, ","))))
+	}
+	return strings.Join(lines, "\n")
+}
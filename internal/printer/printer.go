@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,7 +53,7 @@ func printJSON(results []*model.HostTask) {
 			f.Close()
 		}
 	}()
-	if err := json.NewEncoder(f).Encode(results); err != nil {
+	if err := json.NewEncoder(f).Encode(buildReport(results)); err != nil {
 		slog.Error("Failed to encode into JSON information.", slog.String("ERROR", err.Error()))
 	} else {
 		slog.Info("The JSON file has been successfully generated.", slog.String("PATH", fileP))
@@ -67,6 +68,7 @@ func printTable(hosts []*model.HostTask) {
 		"Type",
 		"Describe",
 		"State",
+		"Exit",
 		"Output",
 		"Error",
 	}
@@ -74,11 +76,17 @@ func printTable(hosts []*model.HostTask) {
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
 		fmt.Printf("\nhost: %s (index: %d) \n", ht.HostIP, ht.Index)
+		if ht.Conn != nil {
+			fmt.Printf("connection: %s\n", ht.Conn.Summary())
+		}
 		t.AppendHeader(headers)
 		for i, result := range ht.Results {
 			status := ":)"
 			detail := firstLine(result.StdOut)
-			if result.StdErr != nil {
+			if detail == "" {
+				detail = firstLine(result.StdErr)
+			}
+			if result.Err != nil {
 				status = ":("
 			}
 			t.AppendRow(
@@ -87,8 +95,9 @@ func printTable(hosts []*model.HostTask) {
 					result.Type,
 					result.Description,
 					status,
+					exitStatus(result),
 					detail,
-					result.StdErr,
+					result.Err,
 				},
 				table.RowConfig{},
 			)
@@ -98,6 +107,18 @@ func printTable(hosts []*model.HostTask) {
 	}
 	slog.Info("The table printing is completed.")
 }
+
+// exitStatus 退出码，被信号终止时附带信号名，没有退出状态时为空
+func exitStatus(result *model.TaskResult) string {
+	if result.ExitCode == nil {
+		return ""
+	}
+	if result.Signal != "" {
+		return fmt.Sprintf("%d (SIG%s)", *result.ExitCode, result.Signal)
+	}
+	return strconv.Itoa(*result.ExitCode)
+}
+
 func firstLine(s string) string {
 	if idx := strings.Index(s, "\n"); idx != -1 {
 		return s[:idx]
@@ -119,7 +140,8 @@ func printExcel(hostsResults []*model.HostTask) {
 	fileP := path.Join(dir, fileName)
 	f := excelize.NewFile()
 	// 表头
-	headers := []string{"ID", "Host", "Task ID", "Task Description", "Task Type", "Task Info", "Output", "State"}
+	headers := []string{"ID", "Host", "Task ID", "Task Description", "Task Type", "Task Info", "Stdout", "Stderr",
+		"Exit Code", "Signal", "Start Time", "End Time", "Duration", "Error", "State", "Connection"}
 	sheet := "Sheet1" // sheet名称
 	for col, header := range headers {
 		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
@@ -135,7 +157,7 @@ func printExcel(hostsResults []*model.HostTask) {
 			var (
 				info   string
 				state  = ":)"
-				output string
+				errMsg string
 			)
 			if result.Type == "upload" || result.Type == "download" {
 				info = fmt.Sprintf("%s: local=%s, remote=%s", result.Type, result.Local, result.Remote)
@@ -143,15 +165,9 @@ func printExcel(hostsResults []*model.HostTask) {
 				info = result.Cmd
 			}
 
-			if result.StdErr != nil {
+			if result.Err != nil {
 				state = ":("
-				if result.StdOut == "" {
-					output = result.StdErr.Error()
-				} else {
-					output = fmt.Sprintf("OUT:\n%s\n\nERR:\n%s", strings.TrimSpace(result.StdOut), strings.TrimSpace(result.StdErr.Error()))
-				}
-			} else {
-				output = strings.TrimSpace(result.StdOut)
+				errMsg = result.Err.Error()
 			}
 			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), hostResult.Index)
 			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), hostResult.HostIP)
@@ -159,14 +175,29 @@ func printExcel(hostsResults []*model.HostTask) {
 			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), result.Description)
 			f.SetCellValue(sheet, fmt.Sprintf("E%d", row), result.Type)
 			f.SetCellValue(sheet, fmt.Sprintf("F%d", row), info)
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), output)
-			f.SetCellValue(sheet, fmt.Sprintf("H%d", row), state)
+			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), strings.TrimSpace(result.StdOut))
+			f.SetCellValue(sheet, fmt.Sprintf("H%d", row), strings.TrimSpace(result.StdErr))
+			if result.ExitCode != nil {
+				f.SetCellValue(sheet, fmt.Sprintf("I%d", row), *result.ExitCode)
+			}
+			f.SetCellValue(sheet, fmt.Sprintf("J%d", row), result.Signal)
+			if !result.StartTime.IsZero() {
+				f.SetCellValue(sheet, fmt.Sprintf("K%d", row), result.StartTime.Format(time.RFC3339Nano))
+				f.SetCellValue(sheet, fmt.Sprintf("L%d", row), result.EndTime.Format(time.RFC3339Nano))
+				f.SetCellValue(sheet, fmt.Sprintf("M%d", row), result.Duration.Round(time.Millisecond).String())
+			}
+			f.SetCellValue(sheet, fmt.Sprintf("N%d", row), errMsg)
+			f.SetCellValue(sheet, fmt.Sprintf("O%d", row), state)
+			if hostResult.Conn != nil {
+				f.SetCellValue(sheet, fmt.Sprintf("P%d", row), hostResult.Conn.Summary())
+			}
 			row++
 		}
 		if taskCount > 1 {
 			endRow := startRow + taskCount - 1
 			f.MergeCell(sheet, fmt.Sprintf("A%d", startRow), fmt.Sprintf("A%d", endRow))
 			f.MergeCell(sheet, fmt.Sprintf("B%d", startRow), fmt.Sprintf("B%d", endRow))
+			f.MergeCell(sheet, fmt.Sprintf("P%d", startRow), fmt.Sprintf("P%d", endRow))
 		}
 	}
 	// 设置单元格样式
@@ -196,11 +227,11 @@ func printExcel(hostsResults []*model.HostTask) {
 	if err != nil {
 		slog.Warn("Failed to create header table style", slog.String("Tips", err.Error()))
 	}
-	err = f.SetCellStyle(sheet, "A1", fmt.Sprintf("H%d", row-1), style)
+	err = f.SetCellStyle(sheet, "A1", fmt.Sprintf("P%d", row-1), style)
 	if err != nil {
 		slog.Warn("Failed to set overall table style", slog.String("Tips", err.Error()))
 	}
-	err = f.SetCellStyle(sheet, "A1", "H1", styleHeader)
+	err = f.SetCellStyle(sheet, "A1", "P1", styleHeader)
 	if err != nil {
 		slog.Warn("Failed to set header table style", slog.String("Tips", err.Error()))
 	}
@@ -0,0 +1,66 @@
+package printer
+
+import (
+	"goss/internal/model"
+	"goss/internal/xerrors"
+	"goss/pkg/report"
+	"time"
+)
+
+// buildReport 将执行结果转换为 pkg/report 定义的版本化格式
+func buildReport(hosts []*model.HostTask) *report.Report {
+	r := &report.Report{
+		SchemaVersion: report.SchemaVersion,
+		GeneratedAt:   time.Now(),
+		Hosts:         make([]report.Host, 0, len(hosts)),
+	}
+	r.Summary.Hosts = len(hosts)
+	for _, ht := range hosts {
+		host := report.Host{
+			Index: ht.Index,
+			Host:  ht.HostIP,
+			Tasks: make([]report.Task, 0, len(ht.Results)),
+		}
+		if ht.Conn != nil {
+			host.Connection = &report.Connection{
+				AuthMethod:  ht.Conn.AuthMethod,
+				KeyExchange: ht.Conn.KeyExchange,
+				HostKey:     ht.Conn.HostKey,
+				Cipher:      ht.Conn.Cipher,
+				MAC:         ht.Conn.MAC,
+			}
+		}
+		for i, result := range ht.Results {
+			task := report.Task{
+				ID:           i + 1,
+				Type:         string(result.Type),
+				Description:  result.Description,
+				Cmd:          result.Cmd,
+				Local:        result.Local,
+				Remote:       result.Remote,
+				RequireSudo:  result.RequireSudo,
+				BecomeMethod: result.BecomeMethod,
+				BecomeUser:   result.BecomeUser,
+				Status:       report.StatusOK,
+				Stdout:       result.StdOut,
+				Stderr:       result.StdErr,
+				ExitCode:     result.ExitCode,
+				Signal:       result.Signal,
+				StartTime:    result.StartTime,
+				EndTime:      result.EndTime,
+				Duration:     result.Duration,
+				Error:        xerrors.ToReport(result.Err),
+			}
+			r.Summary.Tasks++
+			if result.Err != nil {
+				task.Status = report.StatusFailed
+				r.Summary.Failed++
+			} else {
+				r.Summary.OK++
+			}
+			host.Tasks = append(host.Tasks, task)
+		}
+		r.Hosts = append(r.Hosts, host)
+	}
+	return r
+}
@@ -14,7 +14,6 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/pkg/sftp"
-	"golang.org/x/crypto/ssh"
 )
 
 type Transfer struct {
@@ -30,7 +29,8 @@ type result struct {
 	err     error
 }
 
-func NewTransferHandler(remotePath, localPath string, policy config.FileTransferPolicy, conn *ssh.Client) (*Transfer, error) {
+// NewTransferHandler 基于复用的sftp连接创建传输处理器，sftp连接由连接池负责关闭
+func NewTransferHandler(remotePath, localPath string, policy config.FileTransferPolicy, sftpClient *sftp.Client, host string) (*Transfer, error) {
 	var (
 		flag int
 		auto bool
@@ -41,13 +41,8 @@ func NewTransferHandler(remotePath, localPath string, policy config.FileTransfer
 	case config.Never:
 		flag = os.O_CREATE | os.O_WRONLY | os.O_EXCL
 	}
-	// 创建sftp连接
-	sftpClient, err := sftp.NewClient(conn)
-	if err != nil {
-		return nil, err
-	}
 	return &Transfer{
-		host:       strings.Split(conn.Conn.RemoteAddr().String(), ":")[0],
+		host:       host,
 		client:     sftpClient,
 		remotePath: remotePath,
 		localPath:  localPath,
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// ErrNoTerminal 没有可用于交互输入的终端
+var ErrNoTerminal = errors.New("no terminal available for interactive input")
+
+// ReadPassword 从控制终端读取一行输入且不回显，与ssh一样优先使用/dev/tty，标准输入被重定向时仍可输入
+func ReadPassword(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", ErrNoTerminal
+	}
+	defer tty.Close()
+	fd := int(tty.Fd())
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", ErrNoTerminal
+	}
+	// 输入过程中被中断时恢复终端回显
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		signal.Stop(sigs)
+	}()
+	go func() {
+		select {
+		case sig := <-sigs:
+			term.Restore(fd, state)
+			fmt.Fprintln(tty)
+			signal.Stop(sigs)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+
+	fmt.Fprint(tty, prompt)
+	line, err := term.ReadPassword(fd)
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return string(line), nil
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"goss/internal/facts"
 	"strings"
 	"text/template"
 	"time"
@@ -9,18 +10,25 @@ import (
 
 // TemplateData 模板变量结构体
 type TemplateData struct {
-	IP   string // 主机IP地址
-	TIME string // 执行时间戳(格式: YYYYMMDD_HHmmss)
+	IP     string         // 主机清单中的主机名称(IP或主机名)
+	Name   string         // 同IP，主机名场景下更直观
+	TIME   string         // 执行时间戳(格式: YYYYMMDD_HHmmss)
+	Groups []string       // 主机所属分组
+	Vars   map[string]any // 主机清单中的分组变量与主机变量
+	Facts  *facts.Facts   // 采集到的主机信息，未采集时为nil，例如 {{ .Facts.OS.Family }}
 }
 
-// RenderPathTemplate 路径模板渲染
-func RenderPathTemplate(tpl string, ip string) (string, error) {
-	data := TemplateData{
-		IP:   ip,
-		TIME: time.Now().Format("20060102_150405"),
-	}
+// RenderPathTemplate 路径模板渲染，name为主机清单中填写的原始名称
+func RenderPathTemplate(tpl string, name string) (string, error) {
+	return RenderTemplate(tpl, TemplateData{IP: name, Name: name})
+}
 
-	tmpl, err := template.New("path").Parse(tpl)
+// RenderTemplate 使用主机变量渲染模板，TIME为空时使用当前时间，引用不存在的变量时返回错误
+func RenderTemplate(tpl string, data TemplateData) (string, error) {
+	if data.TIME == "" {
+		data.TIME = time.Now().Format("20060102_150405")
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(tpl)
 	if err != nil {
 		return "", err
 	}
@@ -32,6 +40,12 @@ func RenderPathTemplate(tpl string, ip string) (string, error) {
 	return buf.String(), nil
 }
 
+// ValidateTemplate 检查模板语法，变量在执行时才能确定
+func ValidateTemplate(tpl string) error {
+	_, err := template.New("path").Parse(tpl)
+	return err
+}
+
 // ContainsTemplate 检查字符串是否包含模板语法
 func ContainsTemplate(s string) bool {
 	return strings.Contains(s, "{{") && strings.Contains(s, "}}")
@@ -0,0 +1,226 @@
+// Package vault 使用口令派生的密钥(argon2id)和AES-256-GCM加密整个文件或单个配置值
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// 所有版本加密内容共有的标识
+	marker = "$GOSS_VAULT;"
+	// Header 加密文件的首行，同时作为加密数据的附加认证数据
+	Header = "$GOSS_VAULT;1.0;AES256-GCM"
+	// 单个加密值的前缀，例如 password=$GOSS_VAULT;1.0;AES256-GCM;xxxx
+	valuePrefix = Header + ";"
+
+	saltSize = 16
+	keySize  = 32
+	// argon2id参数，修改时需要升级Header中的版本号
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	// 加密文件中base64每行的长度
+	lineWidth = 80
+)
+
+// ErrWrongPassword 口令错误或密文被篡改
+var ErrWrongPassword = errors.New("vault decryption failed: wrong password or corrupted data")
+
+// 在文件中查找单个加密值
+var valuePattern = regexp.MustCompile(regexp.QuoteMeta(valuePrefix) + `[A-Za-z0-9_-]+`)
+
+// Keyring 持有口令并缓存派生的密钥。
+// 同一Keyring加密的多个值共用一个随机盐，解密时每个盐只派生一次密钥，避免大量加密值拖慢加载
+type Keyring struct {
+	password []byte
+	mu       sync.Mutex
+	salt     []byte
+	keys     map[string]cipher.AEAD
+}
+
+// NewKeyring 使用口令创建Keyring
+func NewKeyring(password []byte) (*Keyring, error) {
+	if len(password) == 0 {
+		return nil, fmt.Errorf("vault password must not be empty")
+	}
+	return &Keyring{password: password, keys: make(map[string]cipher.AEAD)}, nil
+}
+
+// IsEncrypted 判断文件内容是否为整体加密，包括不支持的版本
+func IsEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(marker))
+}
+
+// IsEncryptedValue 判断字符串是否为单个加密值，包括不支持的版本
+func IsEncryptedValue(s string) bool {
+	return strings.HasPrefix(s, marker)
+}
+
+// unsupportedFormat 其他版本的goss加密的内容，不能当作明文使用
+func unsupportedFormat(header string) error {
+	return fmt.Errorf("unsupported vault format %q, expected %q", header, Header)
+}
+
+// ContainsEncryptedValues 判断文件内容中是否包含单个加密值
+func ContainsEncryptedValues(content []byte) bool {
+	return valuePattern.Match(content)
+}
+
+// Encrypt 加密整个文件，输出为Header加按行折叠的base64
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	payload, err := k.seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(payload)
+	var buf bytes.Buffer
+	buf.WriteString(Header)
+	buf.WriteByte('\n')
+	for len(encoded) > 0 {
+		n := min(lineWidth, len(encoded))
+		buf.WriteString(encoded[:n])
+		buf.WriteByte('\n')
+		encoded = encoded[n:]
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt 解密整个文件
+func (k *Keyring) Decrypt(content []byte) ([]byte, error) {
+	if !IsEncrypted(content) {
+		return nil, fmt.Errorf("content is not vault encrypted")
+	}
+	if !bytes.HasPrefix(content, []byte(Header+"\n")) {
+		header, _, _ := bytes.Cut(content, []byte("\n"))
+		return nil, unsupportedFormat(string(bytes.TrimSpace(header)))
+	}
+	body := bytes.Join(bytes.Fields(content[len(Header)+1:]), nil)
+	payload, err := base64.StdEncoding.DecodeString(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault data: %w", err)
+	}
+	return k.open(payload)
+}
+
+// EncryptValue 加密单个值，结果只包含 [A-Za-z0-9_-] 与前缀，可直接写入hosts.ini等文件
+func (k *Keyring) EncryptValue(value string) (string, error) {
+	payload, err := k.seal([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return valuePrefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// DecryptValue 解密单个值，非加密值原样返回
+func (k *Keyring) DecryptValue(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, valuePrefix)
+	if !ok {
+		if IsEncryptedValue(value) {
+			return "", unsupportedFormat(value[:strings.LastIndexByte(value, ';')])
+		}
+		return value, nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault value: %w", err)
+	}
+	plaintext, err := k.open(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ReplaceValues 对内容中的每个加密值调用fn并替换为其返回值，用于更换口令
+func ReplaceValues(content []byte, fn func(value string) (string, error)) ([]byte, error) {
+	var firstErr error
+	out := valuePattern.ReplaceAllFunc(content, func(m []byte) []byte {
+		if firstErr != nil {
+			return m
+		}
+		v, err := fn(string(m))
+		if err != nil {
+			firstErr = err
+			return m
+		}
+		return []byte(v)
+	})
+	return out, firstErr
+}
+
+// seal 输出 salt | nonce | 密文
+func (k *Keyring) seal(plaintext []byte) ([]byte, error) {
+	k.mu.Lock()
+	if k.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			k.mu.Unlock()
+			return nil, err
+		}
+		k.salt = salt
+	}
+	salt := k.salt
+	k.mu.Unlock()
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	payload := append(slices.Clone(salt), nonce...)
+	return aead.Seal(payload, nonce, plaintext, []byte(Header)), nil
+}
+
+func (k *Keyring) open(payload []byte) ([]byte, error) {
+	if len(payload) < saltSize {
+		return nil, fmt.Errorf("invalid vault data: too short")
+	}
+	aead, err := k.aead(payload[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	payload = payload[saltSize:]
+	if len(payload) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("invalid vault data: too short")
+	}
+	nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(Header))
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return plaintext, nil
+}
+
+// aead 返回盐对应的AES-GCM实例，派生结果按盐缓存
+func (k *Keyring) aead(salt []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if aead, ok := k.keys[string(salt)]; ok {
+		return aead, nil
+	}
+	key := argon2.IDKey(k.password, salt, argonTime, argonMemory, argonThreads, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k.keys[string(salt)] = aead
+	return aead, nil
+}
@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, password string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring([]byte(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	k := newTestKeyring(t, "s3cret")
+	plaintext := bytes.Repeat([]byte("[web]\n10.0.0.1 password=hunter2\n"), 10)
+	encrypted, err := k.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(encrypted) || bytes.Contains(encrypted, []byte("hunter2")) {
+		t.Fatalf("unexpected encrypted content:\n%s", encrypted)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(encrypted)), "\n")[1:] {
+		if len(line) > lineWidth {
+			t.Fatalf("line longer than %d characters: %q", lineWidth, line)
+		}
+	}
+
+	// 使用同一口令的新Keyring解密
+	decrypted, err := newTestKeyring(t, "s3cret").Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("expected %q, got %q", plaintext, decrypted)
+	}
+}
+
+func TestEncryptValueRoundTrip(t *testing.T) {
+	k := newTestKeyring(t, "s3cret")
+	a, err := k.EncryptValue("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := k.EncryptValue("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("expected different ciphertexts for the same value")
+	}
+	if !IsEncryptedValue(a) || !ContainsEncryptedValues([]byte("password="+a+"\n")) {
+		t.Fatalf("value %q is not recognized as encrypted", a)
+	}
+	for _, v := range []string{a, b} {
+		plain, err := newTestKeyring(t, "s3cret").DecryptValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plain != "hunter2" {
+			t.Errorf("expected hunter2, got %q", plain)
+		}
+	}
+	// 非加密值原样返回
+	if plain, err := k.DecryptValue("hunter2"); err != nil || plain != "hunter2" {
+		t.Errorf("expected the plain value unchanged, got %q %v", plain, err)
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	k := newTestKeyring(t, "s3cret")
+	encrypted, err := k.Encrypt([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := k.EncryptValue("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestKeyring(t, "wrong")
+	if _, err := other.Decrypt(encrypted); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+	if _, err := other.DecryptValue(value); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+	if _, err := NewKeyring(nil); err == nil {
+		t.Error("expected an empty password to be rejected")
+	}
+}
+
+func TestTamperedData(t *testing.T) {
+	k := newTestKeyring(t, "s3cret")
+	value, err := k.EncryptValue("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, valuePrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		mutate  func(p []byte) []byte
+		wantErr error
+		wantMsg string
+	}{
+		{name: "ciphertext", mutate: func(p []byte) []byte { p[len(p)-1] ^= 1; return p }, wantErr: ErrWrongPassword},
+		{name: "nonce", mutate: func(p []byte) []byte { p[saltSize] ^= 1; return p }, wantErr: ErrWrongPassword},
+		{name: "salt", mutate: func(p []byte) []byte { p[0] ^= 1; return p }, wantErr: ErrWrongPassword},
+		{name: "truncated", mutate: func(p []byte) []byte { return p[:saltSize+4] }, wantMsg: "too short"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.mutate(bytes.Clone(payload))
+			_, err := k.DecryptValue(valuePrefix + base64.RawURLEncoding.EncodeToString(p))
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.wantMsg)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantMsg, err)
+			}
+		})
+	}
+
+	encrypted, err := k.Encrypt([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 修改头部后不再被识别为当前版本
+	tampered := []byte(strings.Replace(string(encrypted), Header, "$GOSS_VAULT;1.0;AES128-GCM", 1))
+	if _, err := k.Decrypt(tampered); err == nil || !strings.Contains(err.Error(), "unsupported vault format") {
+		t.Errorf("expected unsupported vault format error, got %v", err)
+	}
+	if _, err := k.Decrypt(append([]byte(Header+"\n!!"), encrypted[len(Header)+1:]...)); err == nil || !strings.Contains(err.Error(), "invalid vault data") {
+		t.Errorf("expected invalid vault data error, got %v", err)
+	}
+	if _, err := k.Decrypt([]byte("plain text\n")); err == nil || !strings.Contains(err.Error(), "not vault encrypted") {
+		t.Errorf("expected not vault encrypted error, got %v", err)
+	}
+}
+
+func TestUnknownVersion(t *testing.T) {
+	k := newTestKeyring(t, "s3cret")
+	encrypted, err := k.Encrypt([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := k.EncryptValue("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHeader := "$GOSS_VAULT;2.0;AES256-GCM"
+	file := []byte(strings.Replace(string(encrypted), Header, newHeader, 1))
+	if !IsEncrypted(file) {
+		t.Fatal("expected other versions to be recognized as encrypted")
+	}
+	if _, err := k.Decrypt(file); err == nil || !strings.Contains(err.Error(), "unsupported vault format") {
+		t.Errorf("expected unsupported vault format error, got %v", err)
+	}
+	// 其他版本的加密值不能被当作明文返回
+	other := strings.Replace(value, Header, newHeader, 1)
+	if !IsEncryptedValue(other) {
+		t.Fatal("expected other versions to be recognized as encrypted values")
+	}
+	if plain, err := k.DecryptValue(other); err == nil || !strings.Contains(err.Error(), "unsupported vault format") {
+		t.Errorf("expected unsupported vault format error, got %q %v", plain, err)
+	}
+}
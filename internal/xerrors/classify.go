@@ -0,0 +1,93 @@
+package xerrors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ClassifyConnErr 将建立ssh连接时的错误归类为拒绝连接、超时、认证失败、主机密钥不匹配或DNS错误
+func ClassifyConnErr(op, target string, err error) *GossError {
+	errType := classify(err)
+	msg := "connection failed"
+	switch errType {
+	case ConnRefusedError:
+		msg = "connection refused"
+	case TimeoutError:
+		msg = "connection timed out"
+	case AuthError:
+		msg = "authentication failed"
+	case HostKeyError:
+		msg = "host key verification failed"
+	case DNSError:
+		msg = "hostname resolution failed"
+	}
+	return Wrap(err, errType, op, target, msg)
+}
+
+// IsRetryable 判断连接错误是否值得重试，认证失败、主机密钥错误以及域名不存在重试也不会成功
+func IsRetryable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var gerr *GossError
+	if errors.As(err, &gerr) {
+		switch gerr.Type {
+		case AuthError, HostKeyError, ConfigurationError, ValidationError:
+			return false
+		}
+		return true
+	}
+	switch classify(err) {
+	case AuthError, HostKeyError:
+		return false
+	}
+	return true
+}
+
+func classify(err error) ErrorType {
+	var gerr *GossError
+	if errors.As(err, &gerr) {
+		return gerr.Type
+	}
+	var keyErr *knownhosts.KeyError
+	var revokedErr *knownhosts.RevokedError
+	if errors.As(err, &keyErr) || errors.As(err, &revokedErr) {
+		return HostKeyError
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DNSError
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnRefusedError
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return TimeoutError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return TimeoutError
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "unable to authenticate"),
+		strings.Contains(msg, "no authentication method available"),
+		strings.Contains(msg, "identity file"),
+		strings.Contains(msg, "user certificate"):
+		return AuthError
+	case strings.Contains(msg, "knownhosts:"), strings.Contains(msg, "host key"), strings.Contains(msg, "revoked"):
+		return HostKeyError
+	case strings.Contains(msg, "connection refused"):
+		return ConnRefusedError
+	case strings.Contains(msg, "timed out"), strings.Contains(msg, "i/o timeout"):
+		return TimeoutError
+	}
+	return ConnectionError
+}
@@ -16,6 +16,13 @@ const (
 	ValidationError    ErrorType = "validation"    // 验证错误
 	ResourceError      ErrorType = "resource"      // 重试多次失败
 	ConfigurationError ErrorType = "configuration" // 配置错误
+	ExitStatusError    ErrorType = "exit_status"   // 命令以非零状态退出或被信号终止
+
+	// 连接错误细分类型
+	ConnRefusedError ErrorType = "connection_refused" // 目标端口拒绝连接
+	AuthError        ErrorType = "auth_failed"        // 认证失败
+	HostKeyError     ErrorType = "host_key_mismatch"  // 主机密钥不匹配或被拒绝
+	DNSError         ErrorType = "dns"                // 域名解析失败
 )
 
 // 基础错误结构体
@@ -0,0 +1,91 @@
+package xerrors
+
+import (
+	"encoding/json"
+	"errors"
+	"goss/pkg/report"
+)
+
+// ToReport 将错误转换为结果文件中的格式，GossError保留分类、上下文与时间，原始错误按包装顺序记录在cause中
+func ToReport(err error) *report.Error {
+	if err == nil {
+		return nil
+	}
+	e, ok := err.(*GossError)
+	if !ok {
+		// 普通错误的信息已包含被包装的错误，cause仅用于保留分类
+		return &report.Error{Message: err.Error(), Cause: ToReport(errors.Unwrap(err))}
+	}
+	r := &report.Error{
+		Type:      string(e.Type),
+		Operation: e.Operation,
+		Target:    e.Target,
+		Message:   e.Message,
+		Cause:     ToReport(e.Cause),
+	}
+	if !e.Timestamp.IsZero() {
+		ts := e.Timestamp
+		r.Timestamp = &ts
+	}
+	if len(e.Details) > 0 {
+		r.Details = e.Details
+	}
+	return r
+}
+
+// FromReport 还原 ToReport 转换的错误，带分类的错误还原为GossError
+func FromReport(r *report.Error) error {
+	if r == nil {
+		return nil
+	}
+	if r.Type == "" {
+		return &causeError{msg: r.Message, cause: FromReport(r.Cause)}
+	}
+	e := &GossError{
+		Type:      ErrorType(r.Type),
+		Operation: r.Operation,
+		Target:    r.Target,
+		Message:   r.Message,
+		Cause:     FromReport(r.Cause),
+		Details:   make(map[string]interface{}),
+	}
+	if r.Timestamp != nil {
+		e.Timestamp = *r.Timestamp
+	}
+	for k, v := range r.Details {
+		e.Details[k] = v
+	}
+	return e
+}
+
+// MarshalJSON 按 report.Error 的格式序列化
+func (e *GossError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ToReport(e))
+}
+
+// UnmarshalJSON 解析 report.Error 格式的错误
+func (e *GossError) UnmarshalJSON(data []byte) error {
+	var r report.Error
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	if r.Type == "" {
+		r.Type = string(ExecutionError)
+	}
+	*e = *FromReport(&r).(*GossError)
+	return nil
+}
+
+// causeError 还原后的普通错误，保留原有信息与错误链
+type causeError struct {
+	msg   string
+	cause error
+}
+
+func (e *causeError) Error() string {
+	return e.msg
+}
+
+func (e *causeError) Unwrap() error {
+	return e.cause
+}
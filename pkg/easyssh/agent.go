@@ -0,0 +1,45 @@
+package easyssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// AgentSocketFromEnv 获取SSH_AUTH_SOCK指向的ssh-agent套接字路径
+func AgentSocketFromEnv() string {
+	return os.Getenv("SSH_AUTH_SOCK")
+}
+
+// agentSigners 连接ssh-agent并获取其中所有密钥的签名器
+// 返回的连接需要在握手完成后关闭
+func agentSigners(socket string) ([]ssh.Signer, net.Conn, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ssh-agent %s: %w", socket, err)
+	}
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	return signers, conn, nil
+}
+
+// ForwardAgent 为会话开启ssh-agent转发，远端进程可以通过转发的agent继续认证
+// 同一个连接只注册一次auth-agent@openssh.com通道处理器
+func (c *Client) ForwardAgent(session *ssh.Session) error {
+	if c.agentSocket == "" {
+		return fmt.Errorf("agent forwarding requested but no ssh-agent socket is available (SSH_AUTH_SOCK)")
+	}
+	c.forwardOnce.Do(func() {
+		c.forwardErr = agent.ForwardToRemote(c.Client, c.agentSocket)
+	})
+	if c.forwardErr != nil {
+		return fmt.Errorf("failed to set up agent forwarding: %w", c.forwardErr)
+	}
+	return agent.RequestAgentForwarding(session)
+}
@@ -0,0 +1,143 @@
+package easyssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveTestAgent 在临时unix套接字上提供ssh-agent服务，返回套接字路径
+func serveTestAgent(t *testing.T, keyring agent.Agent) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	return socket
+}
+
+// newAgentTestServer 只接受指定公钥的服务端，exec时通过转发的agent列出密钥注释
+func newAgentTestServer(t *testing.T, accepted ssh.PublicKey) (string, string) {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if keysEqual(key, accepted) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown public key")
+		},
+	}
+	hostKey, _ := newTestSigner(t)
+	config.AddHostKey(hostKey)
+	return newTestServer(t, config, func(exec *testExec) uint32 {
+		if !exec.agentForwarded {
+			fmt.Fprint(exec.ch.Stderr(), "agent forwarding was not requested")
+			return 1
+		}
+		agentCh, reqs, err := exec.conn.OpenChannel("auth-agent@openssh.com", nil)
+		if err != nil {
+			fmt.Fprint(exec.ch.Stderr(), err)
+			return 1
+		}
+		defer agentCh.Close()
+		go ssh.DiscardRequests(reqs)
+		keys, err := agent.NewClient(agentCh).List()
+		if err != nil {
+			fmt.Fprint(exec.ch.Stderr(), err)
+			return 1
+		}
+		for _, key := range keys {
+			fmt.Fprintln(exec.ch, key.Comment)
+		}
+		return 0
+	})
+}
+
+func TestAgentAuthAndForwarding(t *testing.T) {
+	signer, priv := newTestSigner(t)
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: "goss-test-key"}); err != nil {
+		t.Fatal(err)
+	}
+	socket := serveTestAgent(t, keyring)
+	host, port := newAgentTestServer(t, signer.PublicKey())
+
+	client, err := NewClient(Opts{
+		IP:             host,
+		Port:           port,
+		User:           "deploy",
+		AgentSocket:    socket,
+		ConnectTimeout: 5,
+		Mode:           SecurityModePermissive,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	if client.AuthMethod != AuthAgent {
+		t.Errorf("expected auth method %s, got %s", AuthAgent, client.AuthMethod)
+	}
+
+	// 同一连接上的多个会话都可以使用转发的agent
+	for i := 0; i < 2; i++ {
+		session, err := client.NewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.ForwardAgent(session); err != nil {
+			t.Fatalf("ForwardAgent: %v", err)
+		}
+		out, err := session.Output("ssh-add -l")
+		session.Close()
+		if err != nil {
+			t.Fatalf("session %d: %v", i, err)
+		}
+		if got := strings.TrimSpace(string(out)); got != "goss-test-key" {
+			t.Errorf("session %d: expected the forwarded agent to list goss-test-key, got %q", i, got)
+		}
+	}
+}
+
+func TestAgentUnavailable(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	host, port := newAgentTestServer(t, signer.PublicKey())
+
+	// agent不可用且没有其他认证方式时报错
+	_, err := NewClient(Opts{
+		IP:             host,
+		Port:           port,
+		User:           "deploy",
+		AgentSocket:    filepath.Join(t.TempDir(), "missing.sock"),
+		ConnectTimeout: 5,
+		Mode:           SecurityModePermissive,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no authentication method available") {
+		t.Fatalf("expected no authentication method error, got %v", err)
+	}
+
+	// 没有agent套接字时不能开启转发
+	c := &Client{}
+	if err := c.ForwardAgent(nil); err == nil {
+		t.Fatal("expected ForwardAgent to fail without an agent socket")
+	}
+}
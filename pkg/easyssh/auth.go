@@ -0,0 +1,273 @@
+package easyssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 认证方式名称，与RFC4252中的method name保持一致
+const (
+	AuthPublicKey           = "publickey"
+	AuthPassword            = "password"
+	AuthKeyboardInteractive = "keyboard-interactive"
+	// 通过ssh-agent中的密钥完成公钥认证
+	AuthAgent = "publickey(agent)"
+	// 通过OpenSSH用户证书完成公钥认证
+	AuthCertificate = "publickey(cert)"
+)
+
+// authTracker 记录握手过程中最后一次被调用的认证方式
+// ssh库按顺序尝试认证方式，握手成功时最后被调用的即为成功的方式
+type authTracker struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (t *authTracker) mark(method string) {
+	t.mu.Lock()
+	t.last = method
+	t.mu.Unlock()
+}
+
+func (t *authTracker) method() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// buildAuthMethods 按照 公钥(用户证书、私钥文件、ssh-agent) -> 密码 -> 键盘交互 的顺序构建认证方式
+// agentSigners 为ssh-agent提供的签名器，可以为空
+func buildAuthMethods(opts Opts, agentSigners []ssh.Signer, tracker *authTracker) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	identities, skipped := loadSigners(opts.IdentityFiles, opts.Passphrase)
+	var cert *ssh.Certificate
+	if opts.CertificateFile != "" {
+		var err error
+		if cert, err = LoadCertificate(opts.CertificateFile); err != nil {
+			return nil, err
+		}
+	}
+	certMark := func() { tracker.mark(AuthCertificate) }
+	certUsed := false
+	// ssh库对同名认证方式只尝试一次，所以证书、私钥文件与agent的密钥需要合并为同一个publickey方法
+	var signers []ssh.Signer
+	for _, id := range identities {
+		c := cert
+		if c != nil && keysEqual(c.Key, id.signer.PublicKey()) {
+			certUsed = true
+		} else {
+			// 未指定证书或证书不属于该私钥时，与OpenSSH一样查找私钥旁的 -cert.pub 文件
+			c = defaultCertificate(id.path)
+		}
+		if c != nil {
+			certSigner, err := ssh.NewCertSigner(c, id.signer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to use certificate for identity file %s: %w", id.path, err)
+			}
+			signers = append(signers, trackSigner(certSigner, certMark))
+		}
+		signers = append(signers, trackSigner(id.signer, func() { tracker.mark(AuthPublicKey) }))
+	}
+	for _, s := range agentSigners {
+		// 证书对应的私钥只保存在ssh-agent中
+		if cert != nil && !certUsed && keysEqual(cert.Key, s.PublicKey()) {
+			certSigner, err := ssh.NewCertSigner(cert, s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to use certificate %s with ssh-agent key: %w", opts.CertificateFile, err)
+			}
+			signers = append(signers, trackSigner(certSigner, certMark))
+			certUsed = true
+		}
+		signers = append(signers, trackSigner(s, func() { tracker.mark(AuthAgent) }))
+	}
+	if cert != nil && !certUsed {
+		return nil, fmt.Errorf("user certificate %s does not match any identity file or ssh-agent key", opts.CertificateFile)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if opts.Passwd != "" {
+		methods = append(methods,
+			ssh.PasswordCallback(func() (string, error) {
+				tracker.mark(AuthPassword)
+				return opts.Passwd, nil
+			}),
+			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) (answers []string, err error) {
+				tracker.mark(AuthKeyboardInteractive)
+				answers = make([]string, len(questions))
+				for i, q := range questions {
+					qLower := strings.ToLower(q)
+					switch {
+					case strings.Contains(qLower, "passw") && !echos[i]:
+						answers[i] = opts.Passwd
+					default:
+						answers[i] = ""
+					}
+				}
+				return answers, nil
+			}),
+		)
+	}
+	if len(methods) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("no authentication method available: %w", errors.Join(skipped...))
+		}
+		return nil, errors.New("no authentication method available: neither identity file nor password is configured")
+	}
+	return methods, nil
+}
+
+// trackedSigner 在签名时记录认证方式，服务端接受公钥后才会要求客户端签名
+type trackedSigner struct {
+	ssh.Signer
+	mark func()
+}
+
+func (t *trackedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	t.mark()
+	return t.Signer.Sign(rand, data)
+}
+
+// trackedAlgorithmSigner 保留AlgorithmSigner能力，避免RSA密钥退化为ssh-rsa(SHA1)签名
+type trackedAlgorithmSigner struct {
+	ssh.AlgorithmSigner
+	mark func()
+}
+
+func (t *trackedAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	t.mark()
+	return t.AlgorithmSigner.Sign(rand, data)
+}
+
+func (t *trackedAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	t.mark()
+	return t.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+func trackSigner(s ssh.Signer, mark func()) ssh.Signer {
+	as, ok := s.(ssh.AlgorithmSigner)
+	if !ok {
+		return &trackedSigner{Signer: s, mark: mark}
+	}
+	tracked := &trackedAlgorithmSigner{AlgorithmSigner: as, mark: mark}
+	if ms, ok := s.(ssh.MultiAlgorithmSigner); ok {
+		if multi, err := ssh.NewSignerWithAlgorithms(tracked, ms.Algorithms()); err == nil {
+			return multi
+		}
+	}
+	return tracked
+}
+
+// identity 私钥文件及其签名器
+type identity struct {
+	path   string
+	signer ssh.Signer
+}
+
+// loadSigners 读取私钥文件，支持OpenSSH与PEM格式的RSA/ECDSA/Ed25519密钥
+// 与OpenSSH一样跳过无法读取或解密的私钥文件，返回跳过的原因
+func loadSigners(paths []string, passphrase string) ([]identity, []error) {
+	var (
+		identities []identity
+		skipped    []error
+	)
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		signer, err := LoadPrivateKey(p, passphrase)
+		if err != nil {
+			slog.Warn("Skipping identity file", "path", p, "error", err)
+			skipped = append(skipped, err)
+			continue
+		}
+		identities = append(identities, identity{path: p, signer: signer})
+	}
+	return identities, skipped
+}
+
+// LoadCertificate 读取OpenSSH用户证书(ssh-keygen -s 签发的 *-cert.pub 文件)
+func LoadCertificate(path string) (*ssh.Certificate, error) {
+	path = ExpandHome(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user certificate %s: %w", path, err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user certificate %s: %w", path, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok || cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%s is not an OpenSSH user certificate", path)
+	}
+	if before := cert.ValidBefore; before != ssh.CertTimeInfinity && time.Now().Unix() >= int64(before) {
+		return nil, fmt.Errorf("user certificate %s expired at %s", path, time.Unix(int64(before), 0).Format(time.RFC3339))
+	}
+	return cert, nil
+}
+
+// defaultCertificate 加载私钥旁的 -cert.pub 证书，不存在或不可用时返回nil
+func defaultCertificate(identityFile string) *ssh.Certificate {
+	path := ExpandHome(identityFile) + "-cert.pub"
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	cert, err := LoadCertificate(path)
+	if err != nil {
+		slog.Warn("Ignoring user certificate", "path", path, "error", err)
+		return nil
+	}
+	return cert
+}
+
+func keysEqual(a, b ssh.PublicKey) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+// LoadPrivateKey 加载单个私钥文件，密钥受密码保护时使用passphrase解密
+func LoadPrivateKey(path, passphrase string) (ssh.Signer, error) {
+	path = ExpandHome(path)
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file %s: %w", path, err)
+	}
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err == nil {
+		return signer, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("identity file %s is passphrase protected but no passphrase was provided", path)
+	}
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt identity file %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// ExpandHome 展开路径开头的 ~ 为用户目录
+func ExpandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
@@ -0,0 +1,170 @@
+package easyssh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// BecomeMethod 提权方式
+type BecomeMethod string
+
+const (
+	BecomeSu   BecomeMethod = "su"
+	BecomeSudo BecomeMethod = "sudo"
+	BecomeDoas BecomeMethod = "doas"
+	// 不提权，直接以登录用户执行
+	BecomeNone BecomeMethod = "none"
+)
+
+// BecomeMethods 支持的提权方式
+var BecomeMethods = []BecomeMethod{BecomeSu, BecomeSudo, BecomeDoas, BecomeNone}
+
+// 默认兼容原有行为：su 到 root
+const (
+	DefaultBecomeMethod = BecomeSu
+	DefaultBecomeUser   = "root"
+)
+
+var (
+	// ErrBecomePassword 提权密码错误
+	ErrBecomePassword = errors.New("incorrect become password")
+	// ErrBecomePasswordRequired 提权需要密码但没有提供
+	ErrBecomePasswordRequired = errors.New("become password required but not provided")
+)
+
+// sudo使用固定的提示符，避免受语言与sudoers中passprompt配置的影响
+const sudoPrompt = "[goss-become] password: "
+
+// Become 提权参数
+type Become struct {
+	Method   BecomeMethod
+	User     string
+	Password string
+	// 在内置规则之外追加的密码提示与认证失败正则表达式
+	Prompts  []string
+	Failures []string
+	// 输出停在无法识别的提示处的最长等待时间，0表示使用默认值
+	PromptTimeout time.Duration
+}
+
+// ValidBecomeMethod 判断是否为支持的提权方式
+func ValidBecomeMethod(method string) bool {
+	for _, m := range BecomeMethods {
+		if string(m) == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ShellQuote 使用单引号转义参数，保证命令中的引号、$与反引号原样传给远端shell
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Command 返回以目标用户执行cmd的完整命令
+func (b Become) Command(cmd string) string {
+	user := b.User
+	if user == "" {
+		user = DefaultBecomeUser
+	}
+	switch b.Method {
+	case BecomeNone:
+		return cmd
+	case BecomeSudo:
+		return fmt.Sprintf("env LANG=C sudo -H -p %s -u %s -- sh -c %s", ShellQuote(sudoPrompt), ShellQuote(user), ShellQuote(cmd))
+	case BecomeDoas:
+		return fmt.Sprintf("env LANG=C doas -u %s sh -c %s", ShellQuote(user), ShellQuote(cmd))
+	default:
+		return fmt.Sprintf("env LANG=C su - %s -c %s", ShellQuote(user), ShellQuote(cmd))
+	}
+}
+
+// ExecutePrivilegedCommandOverSSH 以root身份通过su执行命令
+func (session *CtxSession) ExecutePrivilegedCommandOverSSH(command string, sudoPassword string) (string, error) {
+	result, err := session.ExecuteBecome(command, Become{Method: BecomeSu, User: DefaultBecomeUser, Password: sudoPassword})
+	return result.Stdout, err
+}
+
+// ExecuteBecome 按提权方式执行命令，出现密码提示时输入密码，
+// 没有提示(NOPASSWD)时直接返回输出，密码错误时立即结束会话而不是等待超时。
+// 命令在pty中执行，标准错误合并在Stdout中
+func (session *CtxSession) ExecuteBecome(command string, become Become) (*Result, error) {
+	if become.Method == BecomeNone {
+		return session.Execute(command)
+	}
+	if become.Method == "" {
+		become.Method = DefaultBecomeMethod
+	}
+	if _, ok := defaultPromptRules[become.Method]; !ok {
+		session.Close()
+		return &Result{}, fmt.Errorf("unsupported become method %q", become.Method)
+	}
+	rules, err := NewPromptRules(become.Method, become.Prompts, become.Failures)
+	if err != nil {
+		session.Close()
+		return &Result{}, err
+	}
+	defer func() {
+		if session != nil {
+			session.Close()
+		}
+	}()
+	mode := ssh.TerminalModes{
+		ssh.ECHO:          0,     // 禁用回显，防止密码泄露
+		ssh.TTY_OP_ISPEED: 14400, // 输入速度限制
+		ssh.TTY_OP_OSPEED: 14400, // 输出速度限制
+	}
+	// 配置终端,默认使用linux
+	if err := session.RequestPty("linux", 80, 24, mode); err != nil {
+		return &Result{}, fmt.Errorf("failed to remotely request pty of Linux type, %w", err)
+	}
+	session.Setenv("LANG", "C")
+	stdIn, err := session.StdinPipe()
+	if err != nil {
+		return &Result{}, fmt.Errorf("get remote standard input exception, %w", err)
+	}
+	stdOut, err := session.StdoutPipe()
+	if err != nil {
+		return &Result{}, fmt.Errorf("get remote standard output exception, %w", err)
+	}
+	engine := newPromptEngine(rules, become.Password, stdIn, become.PromptTimeout)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		engine.read(stdOut)
+	}()
+	if err := session.Start(become.Command(command)); err != nil {
+		return &Result{}, err
+	}
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- session.Wait()
+	}()
+	select {
+	case <-session.ctx.Done():
+		// 执行信号断开
+		session.Signal(ssh.SIGTERM)
+		time.Sleep(100 * time.Millisecond) // 信号处理时间
+		return &Result{Stdout: engine.output()}, ErrTimeout
+	case err := <-engine.result:
+		// 认证失败或提示超时后提权程序仍在等待输入，直接关闭会话
+		session.Close()
+		return &Result{Stdout: engine.output()}, err
+	case err := <-waitCh:
+		<-readDone
+		// 进程退出前已输出认证失败信息
+		select {
+		case authErr := <-engine.result:
+			return &Result{Stdout: engine.output()}, authErr
+		default:
+		}
+		result := &Result{Stdout: engine.output()}
+		result.setExit(err)
+		return result, err
+	}
+}
@@ -0,0 +1,47 @@
+package easyssh
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ssh.CertChecker 在known_hosts中没有匹配的 @cert-authority 时返回的错误前缀
+const errNoHostAuthority = "ssh: no authorities for hostname"
+
+// hostCertCallback 校验主机证书，证书由known_hosts中 @cert-authority 声明的CA签发时，
+// 由knownhosts内置的ssh.CertChecker校验主机名、有效期与吊销状态，不再需要逐台保存主机密钥。
+// 没有可信CA时与OpenSSH一致，退化为证书中的原始公钥交由fallback校验
+func hostCertCallback(path string, fallback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		cert, ok := key.(*ssh.Certificate)
+		if !ok {
+			return fallback(hostname, remote, key)
+		}
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("failed to load the known_hosts file: %w", err)
+		}
+		err = callback(hostname, remote, cert)
+		if err != nil && strings.HasPrefix(err.Error(), errNoHostAuthority) {
+			slog.Debug("No trusted CA for host certificate, verifying the plain host key", "host", hostname)
+			return fallback(hostname, remote, cert.Key)
+		}
+		if err != nil {
+			return fmt.Errorf("host key verification failed: invalid host certificate for %s: %w", hostname, err)
+		}
+		// @revoked 也可以吊销证书中的主机密钥或签发证书的CA
+		for _, k := range []ssh.PublicKey{cert.Key, cert.SignatureKey} {
+			var revoked *knownhosts.RevokedError
+			if errors.As(callback(hostname, remote, k), &revoked) {
+				return fmt.Errorf("host key verification failed: invalid host certificate for %s: %w", hostname, revoked)
+			}
+		}
+		return nil
+	}
+}
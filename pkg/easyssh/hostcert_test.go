@@ -0,0 +1,134 @@
+package easyssh
+
+import (
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newHostCertServer 启动使用主机证书的服务端，证书由ca签发给principals
+func newHostCertServer(t *testing.T, ca ssh.Signer, principals []string) (string, string, ssh.Signer) {
+	t.Helper()
+	hostKey, _ := newTestSigner(t)
+	cert := &ssh.Certificate{
+		Key:             hostKey.PublicKey(),
+		CertType:        ssh.HostCert,
+		KeyId:           "goss-test-host",
+		ValidPrincipals: principals,
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(certSigner)
+	host, port := newTestServer(t, config, func(*testExec) uint32 { return 0 })
+	return host, port, hostKey
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func connectStrict(host, port, knownHosts string) error {
+	client, err := NewClient(Opts{
+		IP:             host,
+		Port:           port,
+		User:           "deploy",
+		Passwd:         "secret",
+		ConnectTimeout: 5,
+		Mode:           SecurityModeStrict,
+		KnownHostsFile: knownHosts,
+	})
+	if err == nil {
+		client.Close()
+	}
+	return err
+}
+
+func TestHostCertificate(t *testing.T) {
+	ca, _ := newTestSigner(t)
+	otherCA, _ := newTestSigner(t)
+	host, port, hostKey := newHostCertServer(t, ca, []string{"127.0.0.1"})
+	address := knownhosts.Normalize(net.JoinHostPort(host, port))
+	caLine := "@cert-authority " + address + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))
+
+	cases := []struct {
+		name    string
+		lines   []string
+		wantErr string
+	}{
+		{
+			name:  "trusted CA",
+			lines: []string{caLine},
+		},
+		{
+			name:  "trusted CA with wildcard pattern",
+			lines: []string{"@cert-authority [127.0.0.*]:" + port + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))},
+		},
+		{
+			name:    "CA for another host falls back to the unknown plain key",
+			lines:   []string{"@cert-authority other.example " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))},
+			wantErr: "knownhosts: key is unknown",
+		},
+		{
+			name:  "untrusted CA falls back to the pinned plain key",
+			lines: []string{"@cert-authority " + address + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(otherCA.PublicKey()))), knownhosts.Line([]string{address}, hostKey.PublicKey())},
+		},
+		{
+			name:    "revoked host key",
+			lines:   []string{caLine, "@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(hostKey.PublicKey())))},
+			wantErr: "revoked",
+		},
+		{
+			name:    "revoked CA",
+			lines:   []string{caLine, "@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))},
+			wantErr: "revoked",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := connectStrict(host, port, writeKnownHosts(t, tc.lines...))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected the connection to succeed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHostCertificateWrongPrincipal(t *testing.T) {
+	ca, _ := newTestSigner(t)
+	host, port, _ := newHostCertServer(t, ca, []string{"db01.example"})
+	address := knownhosts.Normalize(net.JoinHostPort(host, port))
+	knownHosts := writeKnownHosts(t, "@cert-authority "+address+" "+strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))))
+
+	err := connectStrict(host, port, knownHosts)
+	if err == nil || !strings.Contains(err.Error(), "invalid host certificate") {
+		t.Fatalf("expected an invalid host certificate error, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package easyssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 交互确认主机密钥的默认等待时间
+const DefaultPromptTimeout = 30 * time.Second
+
+// ErrHostKeyRejected 用户拒绝或确认超时
+var ErrHostKeyRejected = errors.New("host key rejected")
+
+// hostKeyPrompter 串行化主机密钥确认，多个连接协程的提问排队逐个展示
+type hostKeyPrompter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	lines     chan string
+	acceptAll bool
+	// 标准输入已结束(EOF)，之后的提问直接拒绝
+	closed bool
+}
+
+var (
+	prompterOnce sync.Once
+	prompter     *hostKeyPrompter
+	// 保护known_hosts文件的读改写
+	knownHostsMu sync.Mutex
+)
+
+// stdinIsTerminal 判断标准输入是否为终端，非终端环境无法交互确认
+func stdinIsTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// getPrompter 返回进程内唯一的确认器，标准输入只由一个协程读取
+func getPrompter() *hostKeyPrompter {
+	prompterOnce.Do(func() {
+		prompter = &hostKeyPrompter{
+			out:   os.Stdout,
+			lines: make(chan string),
+		}
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				prompter.lines <- strings.TrimSpace(scanner.Text())
+			}
+			close(prompter.lines)
+		}()
+	})
+	return prompter
+}
+
+// confirm 询问用户是否信任主机密钥，known不为空表示与已知密钥冲突，冲突时不受"全部接受"影响
+func (p *hostKeyPrompter) confirm(hostname string, key ssh.PublicKey, known []knownhosts.KnownKey, timeout time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	mismatch := len(known) > 0
+	if !mismatch && p.acceptAll {
+		return true
+	}
+	// 丢弃上一次提问超时后用户输入的内容
+	for drained := p.closed; !drained; {
+		select {
+		case _, ok := <-p.lines:
+			if !ok {
+				p.closed = true
+				drained = true
+			}
+		default:
+			drained = true
+		}
+	}
+	if p.closed {
+		slog.Warn("Standard input is closed, rejecting the host key", "host", hostname)
+		return false
+	}
+	if mismatch {
+		fmt.Fprintf(p.out, "\nWARNING: host %s key mismatch! Known fingerprints:\n", hostname)
+		for _, k := range known {
+			fmt.Fprintf(p.out, "  - %s (%s:%d)\n", ssh.FingerprintSHA256(k.Key), k.Filename, k.Line)
+		}
+		fmt.Fprintf(p.out, "Presented %s key fingerprint: %s\nReplace the stored key? (y/n) [n, %s timeout]: ",
+			key.Type(), ssh.FingerprintSHA256(key), timeout)
+	} else {
+		fmt.Fprintf(p.out, "\nNew host %s %s key fingerprint: %s\nAccept and save? (y/n/a=accept all new keys for this run) [n, %s timeout]: ",
+			hostname, key.Type(), ssh.FingerprintSHA256(key), timeout)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			fmt.Fprintln(p.out, "\nNo answer received, the host key is rejected.")
+			return false
+		case answer, ok := <-p.lines:
+			if !ok {
+				p.closed = true
+				return false
+			}
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+				return true
+			case "a", "all":
+				if mismatch {
+					fmt.Fprint(p.out, "Accept all is not allowed for a key mismatch, please enter 'y' or 'n': ")
+					continue
+				}
+				p.acceptAll = true
+				return true
+			case "n", "no", "":
+				return false
+			default:
+				if mismatch {
+					fmt.Fprint(p.out, "Please enter 'y' or 'n': ")
+				} else {
+					fmt.Fprint(p.out, "Please enter 'y', 'n' or 'a': ")
+				}
+			}
+		}
+	}
+}
+
+// interactiveCallback 未知或冲突的密钥交由用户确认，确认后原子写入known_hosts
+// 等待用户确认期间暂停guard的握手计时
+func interactiveCallback(path string, timeout time.Duration, guard *handshakeGuard) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("failed to load the known_hosts file: %w", err)
+		}
+		err = callback(hostname, remote, key)
+		if err == nil {
+			// 密钥已知
+			return nil
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			// 密钥被吊销或其他错误
+			return err
+		}
+		guard.pause()
+		accepted := getPrompter().confirm(hostname, key, keyErr.Want, timeout)
+		guard.resume()
+		if !accepted {
+			return fmt.Errorf("%w: %s (%s)", ErrHostKeyRejected, hostname, ssh.FingerprintSHA256(key))
+		}
+		// 冲突时替换旧条目，未知时追加
+		return writeKnownHost(path, hostname, key, len(keyErr.Want) > 0)
+	}
+}
+
+// strictCallback 仅信任known_hosts中已有的密钥
+func strictCallback(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("failed to load the known_hosts file: %w", err)
+		}
+		return callback(hostname, remote, key)
+	}
+}
+
+// acceptNewCallback 未知主机的密钥直接写入known_hosts，与已知密钥冲突时拒绝连接
+func acceptNewCallback(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		callback, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("failed to load the known_hosts file: %w", err)
+		}
+		err = callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+		slog.Info("Permanently added host key to known_hosts", "host", hostname, "fingerprint", ssh.FingerprintSHA256(key))
+		return writeKnownHost(path, hostname, key, false)
+	}
+}
+
+// writeKnownHost 写入主机密钥，先写临时文件再重命名，保证known_hosts不会出现写入一半的内容
+func writeKnownHost(path, hostname string, key ssh.PublicKey, replace bool) error {
+	var replaceHosts []string
+	if replace {
+		replaceHosts = []string{hostname}
+	}
+	return AppendKnownHosts(path, []string{knownhosts.Line([]string{hostname}, key)}, replaceHosts)
+}
+
+// readKnownHostsLines 按行读取known_hosts文件，文件不存在时返回空
+func readKnownHostsLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read known_hosts file: %w", err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts file: %w", err)
+	}
+	return lines, nil
+}
+
+// atomicWriteFile 在同一目录写入临时文件后重命名替换目标文件
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lineMatchesHost 判断known_hosts中的一行是否属于该主机，支持明文与哈希(|1|)两种格式
+func lineMatchesHost(line, hostname string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+		return false
+	}
+	hostsField := fields[0]
+	// 带有@cert-authority或@revoked标记的行不属于单个主机
+	if strings.HasPrefix(hostsField, "@") {
+		return false
+	}
+	normalized := knownhosts.Normalize(hostname)
+	for _, pattern := range strings.Split(hostsField, ",") {
+		if pattern == normalized {
+			return true
+		}
+		if strings.HasPrefix(pattern, "|1|") && hashedMatch(pattern, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedMatch 校验 |1|salt|hash 格式的哈希主机名
+func hashedMatch(pattern, host string) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// KnownHostsFile 返回known_hosts文件路径，path为空时使用~/.ssh/known_hosts，文件不存在时创建
+func KnownHostsFile(path string) (string, error) {
+	if path == "" {
+		// 获取用户目录
+		userHomeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve user home directory: %w", err)
+		}
+		path = filepath.Join(userHomeDir, ".ssh", "known_hosts")
+	}
+	path = ExpandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	// 判断公钥指纹文件是否存在
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("unable to access the known_hosts file: %w", err)
+	}
+	f.Close()
+	return path, nil
+}
@@ -0,0 +1,27 @@
+package easyssh
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestHostKeyPrompterStdinClosed(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	p := &hostKeyPrompter{out: io.Discard, lines: make(chan string)}
+	close(p.lines)
+
+	// 标准输入结束后每次提问都立即拒绝，不会阻塞后续提问
+	for i := 0; i < 2; i++ {
+		done := make(chan bool, 1)
+		go func() { done <- p.confirm("db01", signer.PublicKey(), nil, time.Minute) }()
+		select {
+		case ok := <-done:
+			if ok {
+				t.Fatal("expected the host key to be rejected after stdin EOF")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("prompt %d did not return after stdin EOF", i)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package easyssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBecomePromptTimeout 输出停在无法识别的提示处的最长等待时间
+const DefaultBecomePromptTimeout = 10 * time.Second
+
+// ErrPromptTimeout 提权程序等待输入，但提示不在识别规则中
+var ErrPromptTimeout = errors.New("unrecognized prompt")
+
+// 常见语言的密码提示，例如 Password: 、密码：，必须位于行首，避免把命令输出中的 db_password: 当作提示
+const passwordPrompt = `(?i)^\s*(password|passwort|mot de passe|contraseña|senha|пароль|密码|密碼|口令|パスワード|암호)(\s+for\s+\S+)?\s*[:：]`
+
+// 疑似等待输入的行，超过提示超时仍无新输出时报错
+var pendingInputPattern = regexp.MustCompile(`[:：?？]\s*$`)
+
+// defaultPromptRules 各提权方式内置的提示与失败规则
+var defaultPromptRules = map[BecomeMethod]struct{ prompts, failures []string }{
+	BecomeSu: {
+		prompts:  []string{passwordPrompt},
+		failures: []string{`(?i)su: (authentication failure|incorrect password|sorry|permission denied)`, `su: .*(认证失败|鉴定故障|密码错误)`},
+	},
+	// sudo通过 -p 使用固定的提示符，只匹配该提示符，免密sudo时命令输出不会被误判
+	BecomeSudo: {
+		prompts:  []string{regexp.QuoteMeta(sudoPrompt)},
+		failures: []string{`Sorry, try again\.`, `incorrect password attempt`, `对不起，请重试`},
+	},
+	BecomeDoas: {
+		prompts:  []string{`doas \([^)]*\) password:`, passwordPrompt},
+		failures: []string{`(?i)doas: authentication failed`},
+	},
+}
+
+// PromptRules 提权时识别的密码提示与认证失败输出
+type PromptRules struct {
+	// 密码提示，只匹配输出最后一行的末尾
+	Prompts []*regexp.Regexp
+	// 输入密码后出现即视为密码错误
+	Failures []*regexp.Regexp
+}
+
+// CompilePatterns 编译正则表达式列表
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// NewPromptRules 内置规则加上额外的提示与失败正则表达式
+func NewPromptRules(method BecomeMethod, prompts, failures []string) (*PromptRules, error) {
+	defaults := defaultPromptRules[method]
+	p, err := CompilePatterns(append(slices.Clone(defaults.prompts), prompts...))
+	if err != nil {
+		return nil, err
+	}
+	f, err := CompilePatterns(append(slices.Clone(defaults.failures), failures...))
+	if err != nil {
+		return nil, err
+	}
+	rules := &PromptRules{Failures: f}
+	// 提示必须位于行尾，提权程序输出提示后等待输入
+	for _, re := range p {
+		rules.Prompts = append(rules.Prompts, regexp.MustCompile(`(?:`+re.String()+`)\s*$`))
+	}
+	return rules, nil
+}
+
+// promptEngine 读取pty输出，出现密码提示时输入密码，识别认证失败，并记录提示与密码回显的位置以便从输出中去掉
+type promptEngine struct {
+	rules    *PromptRules
+	password string
+	in       io.Writer
+	timeout  time.Duration
+	// 认证失败或提示超时，只发送一次
+	result chan error
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	checked  int      // 已检查过的输出位置
+	sent     bool     // 是否已经输入过密码
+	done     bool     // 认证已通过或失败，不再检查输出
+	echoFrom int      // 等待密码回显所在行结束的位置，-1表示没有
+	spans    [][2]int // 需要从输出中去掉的提示与回显
+	timer    *time.Timer
+}
+
+func newPromptEngine(rules *PromptRules, password string, in io.Writer, timeout time.Duration) *promptEngine {
+	if timeout <= 0 {
+		timeout = DefaultBecomePromptTimeout
+	}
+	return &promptEngine{
+		rules:    rules,
+		password: password,
+		in:       in,
+		timeout:  timeout,
+		result:   make(chan error, 1),
+		echoFrom: -1,
+	}
+}
+
+// read 持续读取输出直到会话结束
+func (e *promptEngine) read(r io.Reader) {
+	chunk := make([]byte, 4096)
+	for {
+		n, err := r.Read(chunk)
+		if n > 0 {
+			e.mu.Lock()
+			e.buf.Write(chunk[:n])
+			e.check()
+			e.armTimer()
+			e.mu.Unlock()
+		}
+		if err != nil {
+			e.mu.Lock()
+			if e.timer != nil {
+				e.timer.Stop()
+			}
+			e.mu.Unlock()
+			return
+		}
+	}
+}
+
+// check 检查新输出，调用方持有锁。认证通过后不再检查，避免命令输出被误判
+func (e *promptEngine) check() {
+	if e.echoFrom >= 0 {
+		e.consumeEcho()
+	}
+	if e.done {
+		return
+	}
+	pending := e.buf.String()[e.checked:]
+	if e.sent {
+		if matchAny(e.rules.Failures, pending) || e.promptStart() >= 0 {
+			e.fail(ErrBecomePassword)
+			return
+		}
+		// 密码之后出现了完整的一行其他输出，认为认证已通过
+		if e.echoFrom < 0 {
+			if line, _, ok := strings.Cut(strings.TrimLeft(pending, "\r\n"), "\n"); ok && strings.TrimSpace(line) != "" {
+				e.done = true
+			}
+		}
+		return
+	}
+	start := e.promptStart()
+	if start < 0 {
+		return
+	}
+	e.checked = e.buf.Len()
+	e.spans = append(e.spans, [2]int{start, e.buf.Len()})
+	if e.password == "" {
+		e.fail(ErrBecomePasswordRequired)
+		return
+	}
+	e.sent = true
+	e.echoFrom = e.buf.Len()
+	if _, err := e.in.Write([]byte(e.password + "\n")); err != nil {
+		e.fail(err)
+	}
+}
+
+// promptStart 最后一行以密码提示结尾时返回该行的起始位置，否则返回-1
+func (e *promptEngine) promptStart() int {
+	out := e.buf.String()
+	lineStart := strings.LastIndexByte(out, '\n') + 1
+	if lineStart < e.checked {
+		lineStart = e.checked
+	}
+	if matchAny(e.rules.Prompts, out[lineStart:]) {
+		return lineStart
+	}
+	return -1
+}
+
+// consumeEcho 输入密码后的第一行为空、密码或*号时视为回显，与提示一起去掉
+func (e *promptEngine) consumeEcho() {
+	out := e.buf.String()
+	idx := strings.IndexByte(out[e.echoFrom:], '\n')
+	if idx < 0 {
+		return
+	}
+	end := e.echoFrom + idx + 1
+	echo := strings.TrimSpace(out[e.echoFrom : end-1])
+	if echo == "" || echo == e.password || strings.Trim(echo, "*") == "" {
+		e.spans[len(e.spans)-1][1] = end
+		e.checked = max(e.checked, end)
+	}
+	e.echoFrom = -1
+}
+
+// armTimer 输出停在疑似提示的未结束行时开始计时，超时仍无新输出则报错，调用方持有锁
+func (e *promptEngine) armTimer() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	if e.done {
+		return
+	}
+	// 只看尚未处理的输出，已回答的提示不再计时
+	out := e.buf.String()
+	line := strings.TrimSpace(out[max(strings.LastIndexByte(out, '\n')+1, e.checked):])
+	if line == "" || !pendingInputPattern.MatchString(line) {
+		return
+	}
+	size := e.buf.Len()
+	e.timer = time.AfterFunc(e.timeout, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if !e.done && e.buf.Len() == size {
+			e.fail(fmt.Errorf("%w %q after %s, add it to execution.become.prompts", ErrPromptTimeout, line, e.timeout))
+		}
+	})
+}
+
+func (e *promptEngine) fail(err error) {
+	e.done = true
+	e.result <- err
+}
+
+// output 返回去掉密码提示与回显后的输出
+func (e *promptEngine) output() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := e.buf.String()
+	var sb strings.Builder
+	last := 0
+	for _, span := range e.spans {
+		sb.WriteString(out[last:span[0]])
+		last = span[1]
+	}
+	sb.WriteString(out[last:])
+	return sb.String()
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package easyssh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// runPromptEngine 依次写入输出片段，返回输入的内容、去掉提示后的输出与认证结果
+func runPromptEngine(t *testing.T, method BecomeMethod, password string, timeout time.Duration, chunks ...string) (string, string, error) {
+	t.Helper()
+	rules, err := NewPromptRules(method, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdin bytes.Buffer
+	engine := newPromptEngine(rules, password, &stdin, timeout)
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		engine.read(r)
+		close(done)
+	}()
+	for _, chunk := range chunks {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 等待超时检查完成后再结束输出
+	if timeout > 0 {
+		time.Sleep(3 * timeout)
+	}
+	w.Close()
+	<-done
+	select {
+	case err = <-engine.result:
+	default:
+		err = nil
+	}
+	return stdin.String(), engine.output(), err
+}
+
+func TestPromptEngineNoPrompt(t *testing.T) {
+	// 免密sudo时命令输出中类似提示的内容不会触发输入密码
+	for _, method := range []BecomeMethod{BecomeSudo, BecomeSu} {
+		out := "config loaded\r\ndb_password:"
+		stdin, output, err := runPromptEngine(t, method, "s3cret", 0, "config loaded\r\n", "db_password:")
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", method, err)
+		}
+		if stdin != "" {
+			t.Errorf("%s: expected no input, got %q", method, stdin)
+		}
+		if output != out {
+			t.Errorf("%s: expected output %q, got %q", method, out, output)
+		}
+	}
+	// sudo只识别 -p 指定的提示符
+	stdin, _, _ := runPromptEngine(t, BecomeSudo, "s3cret", 0, "Password:")
+	if stdin != "" {
+		t.Errorf("expected sudo to ignore a generic password prompt, got %q", stdin)
+	}
+}
+
+func TestPromptEnginePrompts(t *testing.T) {
+	cases := []struct {
+		name   string
+		method BecomeMethod
+		chunks []string
+		want   string
+	}{
+		{name: "sudo", method: BecomeSudo, chunks: []string{sudoPrompt, "\r\n", "root\r\n"}, want: "root\r\n"},
+		{name: "sudo split prompt", method: BecomeSudo, chunks: []string{"[goss-be", "come] pass", "word: ", "\r\nroot\r\n"}, want: "root\r\n"},
+		{name: "su", method: BecomeSu, chunks: []string{"Password: ", "\r\n", "root\r\n"}, want: "root\r\n"},
+		{name: "su localized", method: BecomeSu, chunks: []string{"密码：", "\r\nroot\r\n"}, want: "root\r\n"},
+		{name: "su echoed password", method: BecomeSu, chunks: []string{"Password: ", "s3cret\r\n", "root\r\n"}, want: "root\r\n"},
+		{name: "doas masked echo", method: BecomeDoas, chunks: []string{"doas (deploy@web01) password: ", "******\r\n", "root\r\n"}, want: "root\r\n"},
+		{name: "output before the prompt is kept", method: BecomeSu, chunks: []string{"Last login: today\r\n", "Password: ", "\r\nroot\r\n"}, want: "Last login: today\r\nroot\r\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdin, output, err := runPromptEngine(t, tc.method, "s3cret", 0, tc.chunks...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdin != "s3cret\n" {
+				t.Errorf("expected the password to be sent once, got %q", stdin)
+			}
+			if output != tc.want {
+				t.Errorf("expected output %q, got %q", tc.want, output)
+			}
+		})
+	}
+}
+
+func TestPromptEngineFailures(t *testing.T) {
+	cases := []struct {
+		name     string
+		method   BecomeMethod
+		password string
+		chunks   []string
+		wantErr  error
+	}{
+		{name: "sudo wrong password", method: BecomeSudo, password: "s3cret", chunks: []string{sudoPrompt, "\r\n", "Sorry, try again.\r\n"}, wantErr: ErrBecomePassword},
+		{name: "sudo prompts again", method: BecomeSudo, password: "s3cret", chunks: []string{sudoPrompt, "\r\n", sudoPrompt}, wantErr: ErrBecomePassword},
+		{name: "su authentication failure", method: BecomeSu, password: "s3cret", chunks: []string{"Password: ", "\r\n", "su: Authentication failure\r\n"}, wantErr: ErrBecomePassword},
+		{name: "doas authentication failed", method: BecomeDoas, password: "s3cret", chunks: []string{"doas (deploy@web01) password: ", "\r\n", "doas: Authentication failed\r\n"}, wantErr: ErrBecomePassword},
+		{name: "password required", method: BecomeSudo, chunks: []string{sudoPrompt}, wantErr: ErrBecomePasswordRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := runPromptEngine(t, tc.method, tc.password, 0, tc.chunks...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPromptEngineTimeout(t *testing.T) {
+	// 输出停在无法识别的提示处
+	stdin, _, err := runPromptEngine(t, BecomeSu, "s3cret", 50*time.Millisecond, "Enter passphrase for key:")
+	if !errors.Is(err, ErrPromptTimeout) {
+		t.Fatalf("expected ErrPromptTimeout, got %v", err)
+	}
+	if stdin != "" {
+		t.Errorf("expected no input for an unrecognized prompt, got %q", stdin)
+	}
+	// 已回答的提示不再计时
+	_, _, err = runPromptEngine(t, BecomeSu, "s3cret", 50*time.Millisecond, "Password: ")
+	if err != nil {
+		t.Fatalf("expected no timeout after the password was sent, got %v", err)
+	}
+}
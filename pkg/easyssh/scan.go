@@ -0,0 +1,177 @@
+package easyssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 扫描主机密钥时默认请求的算法，每种算法单独握手一次
+var DefaultScanAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoRSASHA512,
+}
+
+// 获取到主机密钥后中断握手，不进行认证
+var errKeyCollected = errors.New("host key collected")
+
+// ScanHostKeys 收集主机提供的公钥，仅完成密钥交换，不进行认证
+// via不为空时经由跳板机连接，服务端不支持的算法会被跳过
+func ScanHostKeys(addr string, via *Client, algorithms []string, timeout time.Duration) ([]ssh.PublicKey, error) {
+	if len(algorithms) == 0 {
+		algorithms = DefaultScanAlgorithms
+	}
+	var (
+		keys    []ssh.PublicKey
+		lastErr error
+	)
+	seen := make(map[string]bool)
+	for _, algo := range algorithms {
+		key, err := scanHostKey(addr, via, algo, timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// rsa-sha2-256/512 返回同一个密钥
+		if fp := ssh.FingerprintSHA256(key); !seen[fp] {
+			seen[fp] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, lastErr
+	}
+	return keys, nil
+}
+
+func scanHostKey(addr string, via *Client, algorithm string, timeout time.Duration) (ssh.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var (
+		conn net.Conn
+		err  error
+	)
+	if via != nil {
+		conn, err = via.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// 握手超时时关闭连接
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var key ssh.PublicKey
+	_, _, _, err = ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:              "goss-keyscan",
+		HostKeyAlgorithms: []string{algorithm},
+		HostKeyCallback: func(hostname string, remote net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errKeyCollected
+		},
+	})
+	if key != nil {
+		return key, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no host key received")
+	}
+	return nil, err
+}
+
+// KnownHostsLine 生成known_hosts条目，hash为true时主机名使用|1|哈希格式
+func KnownHostsLine(addr string, key ssh.PublicKey, hash bool) string {
+	host := knownhosts.Normalize(addr)
+	if hash {
+		host = knownhosts.HashHostname(host)
+	}
+	return knownhosts.Line([]string{host}, key)
+}
+
+// KeyStatus 扫描到的密钥与known_hosts比对的结果
+type KeyStatus string
+
+const (
+	KeyKnown   KeyStatus = "known"   // 与已保存的密钥一致
+	KeyUnknown KeyStatus = "unknown" // 未保存过该主机的密钥
+	KeyChanged KeyStatus = "changed" // 与已保存的密钥不一致
+	KeyRevoked KeyStatus = "revoked" // 密钥已被吊销
+)
+
+// CheckKnownHost 将主机密钥与known_hosts文件比对，changed时返回已保存的密钥
+func CheckKnownHost(path, addr string, key ssh.PublicKey) (KeyStatus, []knownhosts.KnownKey, error) {
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load the known_hosts file: %w", err)
+	}
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	remote := net.Addr(tcpAddr)
+	if tcpAddr == nil {
+		remote = &net.TCPAddr{}
+	}
+	err = callback(addr, remote, key)
+	if err == nil {
+		return KeyKnown, nil, nil
+	}
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) {
+		if len(keyErr.Want) == 0 {
+			return KeyUnknown, nil, nil
+		}
+		// 同一主机的其他类型密钥已保存时，knownhosts同样返回KeyError，只比较同类型密钥
+		var sameType []knownhosts.KnownKey
+		for _, k := range keyErr.Want {
+			if k.Key.Type() == key.Type() {
+				sameType = append(sameType, k)
+			}
+		}
+		if len(sameType) == 0 {
+			return KeyUnknown, nil, nil
+		}
+		return KeyChanged, sameType, nil
+	}
+	var revokedErr *knownhosts.RevokedError
+	if errors.As(err, &revokedErr) {
+		return KeyRevoked, nil, nil
+	}
+	return "", nil, err
+}
+
+// AppendKnownHosts 原子追加多行known_hosts条目，replace中的主机会先删除旧条目
+func AppendKnownHosts(path string, lines []string, replace []string) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	content, err := readKnownHostsLines(path)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	for _, line := range content {
+		drop := false
+		for _, host := range replace {
+			if lineMatchesHost(line, host) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			out = append(out, line...)
+			out = append(out, '\n')
+		}
+	}
+	for _, line := range lines {
+		out = append(out, line...)
+		out = append(out, '\n')
+	}
+	return atomicWriteFile(path, out, 0600)
+}
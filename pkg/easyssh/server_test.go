@@ -0,0 +1,100 @@
+package easyssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testExec 测试服务端收到的exec请求
+type testExec struct {
+	conn *ssh.ServerConn
+	cmd  string
+	// 会话是否请求过agent转发(auth-agent-req@openssh.com)
+	agentForwarded bool
+	ch             ssh.Channel
+}
+
+// newTestSigner 生成测试用的ed25519密钥
+func newTestSigner(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, priv
+}
+
+// newTestServer 启动进程内ssh服务端，返回监听的地址与端口。
+// 只支持session通道上的exec请求，handler的返回值作为退出码
+func newTestServer(t *testing.T, config *ssh.ServerConfig, handler func(*testExec) uint32) (string, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn, config, handler)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return host, port
+}
+
+func serveTestConn(conn net.Conn, config *ssh.ServerConfig, handler func(*testExec) uint32) {
+	defer conn.Close()
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		ch, requests, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go serveTestSession(&testExec{conn: sconn, ch: ch}, requests, handler)
+	}
+}
+
+func serveTestSession(exec *testExec, requests <-chan *ssh.Request, handler func(*testExec) uint32) {
+	defer exec.ch.Close()
+	for req := range requests {
+		switch req.Type {
+		case "auth-agent-req@openssh.com":
+			exec.agentForwarded = true
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			exec.cmd = payload.Command
+			status := handler(exec)
+			exec.ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
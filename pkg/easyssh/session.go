@@ -4,10 +4,8 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
-	"io"
 	"log/slog"
-	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -26,108 +24,76 @@ func NewCTXSession(ctx context.Context, con *ssh.Client) (*CtxSession, error) {
 	}, err
 }
 
-// 带有超时的命令执行控制
-func (session *CtxSession) Execute(cmd string) (string, error) {
-	defer func() {
-		if session != nil {
-			session.Close()
-		}
-	}()
-	errCH := make(chan error, 1)
-	var OutPutResult string
-	go func() {
-		defer close(errCH)
-		out, err := session.CombinedOutput(cmd)
-		if err != nil {
-			errCH <- errors.New(string(out))
-			return
-		}
-		slog.Debug("命令执行完成",
-			"out", out)
-		OutPutResult = string(out)
-	}()
+// ErrTimeout 命令在上下文超时前没有结束
+var ErrTimeout = errors.New("timeout")
 
-	for {
-		select {
-		case <-session.ctx.Done():
-			// 执行信号断开
-			session.Signal(ssh.SIGTERM)
-			time.Sleep(100 * time.Millisecond) // 信号处理时间
-			return OutPutResult, fmt.Errorf("timeout")
-		case err := <-errCH:
-			return OutPutResult, err
-		}
+// Result 命令执行结果
+type Result struct {
+	Stdout string
+	// 使用pty执行(提权)时标准错误与标准输出合并，此时为空
+	Stderr string
+	// 退出码，超时或没有收到退出状态时为nil
+	ExitCode *int
+	// 命令被信号终止时的信号名，例如 TERM、KILL
+	Signal string
+}
+
+// setExit 根据会话结束时的错误设置退出码与信号
+func (r *Result) setExit(err error) {
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		r.ExitCode = &code
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitStatus()
+		r.ExitCode = &code
+		r.Signal = exitErr.Signal()
 	}
 }
 
-func (session *CtxSession) ExecutePrivilegedCommandOverSSH(command string, sudoPassword string) (string, error) {
+// syncBuffer 超时返回时命令可能仍在写入输出
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Execute 带有超时的命令执行控制，分别收集标准输出与标准错误，命令以非零状态退出时返回 *ssh.ExitError
+func (session *CtxSession) Execute(cmd string) (*Result, error) {
 	defer func() {
 		if session != nil {
 			session.Close()
 		}
 	}()
-	mode := ssh.TerminalModes{
-		ssh.ECHO:          0,     // 禁用回显，防止密码泄露
-		ssh.TTY_OP_ISPEED: 14400, // 输入速度限制
-		ssh.TTY_OP_OSPEED: 14400, // 输出速度限制
-	}
-	// 配置终端,默认使用linux
-	if err := session.RequestPty("linux", 80, 24, mode); err != nil {
-		return "", fmt.Errorf("failed to remotely request pty of Linux type, %w", err)
-	}
-	session.Setenv("LANG", "C")
-	stdIn, err := session.StdinPipe()
-	if err != nil {
-		return "", fmt.Errorf("get remote standard input exception, %w", err)
-	}
-	errChan := make(chan error, 1)
-	stdOutBuf := new(bytes.Buffer)
-	session.Stdout = stdOutBuf
-	go func(in io.WriteCloser, out *bytes.Buffer) {
-		for {
-			if strings.Contains(out.String(), "Password") {
-				// 注入root密码
-				_, err := in.Write([]byte(sudoPassword + "\n"))
-				if err != nil {
-					errChan <- err
-					break
-				}
-				break
-			}
-		}
-	}(stdIn, stdOutBuf)
+	var stdout, stderr syncBuffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	errCH := make(chan error, 1)
 	go func() {
-		defer close(errChan)
-		errChan <- session.Run(fmt.Sprintf("env LANG=C  su - root -c \"%s\"", command))
+		errCH <- session.Run(cmd)
 	}()
-	// 拼接命令
-	for {
-		select {
-		case <-session.ctx.Done():
-			// 执行信号断开
-			session.Signal(ssh.SIGTERM)
-			time.Sleep(100 * time.Millisecond) // 信号处理时间
-			return cleanOutput(stdOutBuf.String()), fmt.Errorf("timeout")
-		case err := <-errChan:
-			return cleanOutput(stdOutBuf.String()), err
-		}
-	}
-}
-
-func cleanOutput(output string) string {
-	passwordPrompts := "Password:"
-
-	lines := strings.Split(output, "\n")
-	cleanLines := []string{}
-	for _, line := range lines {
-		isPrompt := false
-		if strings.Contains(line, passwordPrompts) {
-			isPrompt = true
-			continue
-		}
-		if !isPrompt {
-			cleanLines = append(cleanLines, line)
-		}
+	select {
+	case <-session.ctx.Done():
+		// 执行信号断开
+		session.Signal(ssh.SIGTERM)
+		time.Sleep(100 * time.Millisecond) // 信号处理时间
+		return &Result{Stdout: stdout.String(), Stderr: stderr.String()}, ErrTimeout
+	case err := <-errCH:
+		result := &Result{Stdout: stdout.String(), Stderr: stderr.String()}
+		result.setExit(err)
+		slog.Debug("命令执行完成", "out", result.Stdout, "exit", result.ExitCode)
+		return result, err
 	}
-	return strings.Join(cleanLines, "\n")
 }
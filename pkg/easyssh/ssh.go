@@ -1,18 +1,14 @@
 package easyssh
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
 	"net"
-	"os"
-	"path"
-	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 type SecurityMode int
@@ -26,169 +22,273 @@ const (
 
 	// SecurityModeStrict 仅信任本地已知密钥（生产环境推荐）
 	SecurityModeStrict // 2
+
+	// SecurityModeAcceptNew 自动保存未知主机的密钥，拒绝与已知密钥冲突的连接
+	SecurityModeAcceptNew // 3
 )
 
 type Opts struct {
+	// 主机名或IP，同时用于主机密钥校验
 	IP string
+	// 实际连接的地址，为空时连接IP
+	DialAddr string
 	// default: 22
 	Port   string
 	User   string
 	Passwd string
+	// 私钥文件路径，按顺序优先于密码认证尝试
+	IdentityFiles []string
+	// 私钥文件的保护密码
+	Passphrase string
+	// OpenSSH用户证书路径，为空时自动加载私钥旁的 -cert.pub 文件
+	CertificateFile string
+	// ssh-agent套接字路径，为空时不使用agent认证，通常取自SSH_AUTH_SOCK
+	AgentSocket string
 	// 连接阶段超时(秒)
 	ConnectTimeout int
 	// default: SecurityModeInteractive
 	Mode SecurityMode
+	// 交互模式下等待用户确认主机密钥的时间，超时默认拒绝
+	PromptTimeout time.Duration
+	// known_hosts文件路径，为空时使用~/.ssh/known_hosts
+	KnownHostsFile string
+	// 跳板机连接，不为空时通过该连接转发到目标主机
+	Via *Client
+	// 算法列表按优先级排列，为空时使用ssh库的默认算法
+	Ciphers           []string
+	KeyExchanges      []string
+	MACs              []string
+	HostKeyAlgorithms []string
+}
+
+// Client 对ssh.Client的封装，额外记录连接的认证信息
+type Client struct {
+	*ssh.Client
+	// 握手成功时使用的认证方式
+	AuthMethod string
+
+	agentSocket string
+	forwardOnce sync.Once
+	forwardErr  error
 }
 
-func NewClient(opts Opts) (*ssh.Client, error) {
+func NewClient(opts Opts) (*Client, error) {
+	tracker := &authTracker{}
+	var signers []ssh.Signer
+	if opts.AgentSocket != "" {
+		s, conn, err := agentSigners(opts.AgentSocket)
+		if err != nil {
+			// agent不可用时继续尝试其他认证方式
+			slog.Warn("ssh-agent is unavailable, skipping agent authentication", "host", opts.IP, "error", err)
+		} else {
+			// agent签名只在握手阶段使用
+			defer conn.Close()
+			signers = s
+		}
+	}
+	auth, err := buildAuthMethods(opts, signers, tracker)
+	if err != nil {
+		return nil, err
+	}
 	//创建ssh连接
-	return ssh.Dial("tcp", net.JoinHostPort(opts.IP, opts.Port), &ssh.ClientConfig{
-		User: opts.User,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(opts.Passwd),
-			ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) (answers []string, err error) {
-				answers = make([]string, len(questions))
-				for i, q := range questions {
-					qLower := strings.ToLower(q)
-					switch {
-					case strings.Contains(qLower, "passwd") && !echos[i]:
-						answers[i] = opts.Passwd
-					default:
-						answers[i] = ""
-					}
-				}
-				return answers, nil
-			}),
+	dialAddr := opts.IP
+	if opts.DialAddr != "" {
+		dialAddr = opts.DialAddr
+	}
+	guard := &handshakeGuard{timeout: time.Second * time.Duration(opts.ConnectTimeout)}
+	client, err := dial(net.JoinHostPort(dialAddr, opts.Port), net.JoinHostPort(opts.IP, opts.Port), opts.Via, guard, &ssh.ClientConfig{
+		Config: ssh.Config{
+			Ciphers:      opts.Ciphers,
+			KeyExchanges: opts.KeyExchanges,
+			MACs:         opts.MACs,
 		},
-		HostKeyCallback: keyProcessing(opts.Mode),
-		Timeout:         time.Second * time.Duration(opts.ConnectTimeout),
+		User:              opts.User,
+		Auth:              auth,
+		HostKeyCallback:   keyProcessing(opts.Mode, opts.KnownHostsFile, opts.PromptTimeout, guard),
+		HostKeyAlgorithms: opts.HostKeyAlgorithms,
+		Timeout:           time.Second * time.Duration(opts.ConnectTimeout),
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		Client:      client,
+		AuthMethod:  tracker.method(),
+		agentSocket: opts.AgentSocket,
+	}, nil
+}
+
+// Algorithms 与服务端协商得到的算法
+type Algorithms struct {
+	KeyExchange string
+	HostKey     string
+	// 客户端到服务端方向的加密与校验算法
+	Cipher string
+	MAC    string
+}
+
+// Algorithms 返回握手时协商的算法
+func (c *Client) Algorithms() Algorithms {
+	meta, ok := c.Client.Conn.(ssh.AlgorithmsConnMetadata)
+	if !ok {
+		return Algorithms{}
+	}
+	negotiated := meta.Algorithms()
+	return Algorithms{
+		KeyExchange: negotiated.KeyExchange,
+		HostKey:     negotiated.HostKey,
+		Cipher:      negotiated.Write.Cipher,
+		MAC:         negotiated.Write.MAC,
+	}
+}
+
+// dial 建立ssh连接，via不为空时经由跳板机的direct-tcpip通道连接目标地址
+// addr为实际连接的地址，hostname用于主机密钥校验
+func dial(addr, hostname string, via *Client, guard *handshakeGuard, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if via == nil {
+		conn, err = net.DialTimeout("tcp", addr, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+		defer cancel()
+		conn, err = via.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial %s via jump host %s: %w", addr, via.RemoteAddr(), err)
+		}
+	}
+	// 跳板通道不支持SetDeadline，握手超时时统一通过关闭连接中断握手
+	guard.start(conn)
+	c, chans, reqs, err := ssh.NewClientConn(conn, hostname, config)
+	if guard.stop() {
+		if err == nil {
+			c.Close()
+		}
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s timed out after %s", addr, guard.timeout)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// handshakeGuard 握手超时后关闭连接，等待用户确认主机密钥期间暂停计时
+type handshakeGuard struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	timer   *time.Timer
+	expired bool
 }
 
-func keyProcessing(mode SecurityMode) func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+// start 开始握手计时，timeout不大于0时不限制握手时间
+func (g *handshakeGuard) start(conn net.Conn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.conn = conn
+	g.arm()
+}
+
+// pause 用户确认主机密钥前暂停计时
+func (g *handshakeGuard) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+}
+
+// resume 用户确认后重新开始计时
+func (g *handshakeGuard) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conn != nil && !g.expired {
+		g.arm()
+	}
+}
+
+// stop 握手结束后停止计时，返回握手是否因超时被中断
+func (g *handshakeGuard) stop() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.conn = nil
+	return g.expired
+}
+
+// arm 调用方持有锁
+func (g *handshakeGuard) arm() {
+	if g.timeout <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(g.timeout, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		// 已暂停、重新计时或握手已结束
+		if g.timer != timer {
+			return
+		}
+		g.expired = true
+		g.conn.Close()
+	})
+	g.timer = timer
+}
+
+func keyProcessing(mode SecurityMode, knownHostsFile string, promptTimeout time.Duration, guard *handshakeGuard) ssh.HostKeyCallback {
 	switch mode {
 	case SecurityModePermissive:
 		return ssh.InsecureIgnoreHostKey()
 	case SecurityModeInteractive:
-		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			// 获取公钥指纹文件路径
-			f, path, err := getPublicKeyFingerprintFile()
-			if err != nil {
-				return err
-			}
-			// 比对指纹
-			callback, err := knownhosts.New(path)
-			if err != nil {
-				return err
-			}
-			err = callback(hostname, remote, key)
-			if err == nil {
-				// 密钥已知
-				return nil
-			}
-			if keyErr, ok := err.(*knownhosts.KeyError); ok {
-				if len(keyErr.Want) > 0 {
-					slog.Warn(fmt.Sprintf("Host: %s key mismatch!", hostname))
-					for _, knownKey := range keyErr.Want {
-						fmt.Printf("- %s\n", ssh.FingerprintSHA256(knownKey.Key))
-					}
-				}
-			} else if revokedErr, ok := err.(*knownhosts.RevokedError); ok {
-				return fmt.Errorf("connection refused: Host %s  key has been revoked (%s)", hostname, ssh.FingerprintSHA256(revokedErr.Revoked.Key))
-			} else {
-				// 其他错误
-				return fmt.Errorf("failed to verify host key: %w", err)
-			}
-			// 密钥不存在或不匹配，提示用户
-			fmt.Printf("New host %s key fingerprint: %s\nAccept and save? (y/n):", hostname, ssh.FingerprintSHA256(key))
-			var output string
-			for {
-				fmt.Scanln(output)
-				if output != "y" && output != "n" {
-					fmt.Print("Please enter 'y' or 'n' : ")
-					time.Sleep(time.Second * 1)
-					continue
-				}
-				break
-			}
-			if output == "y" {
-				// 读取公钥指纹文件
-				bs, err := os.ReadFile(path)
-				if err != nil {
-					return fmt.Errorf("failed to read known_hosts file: %w", err)
-				}
-				var newLines []string
-				scanner := bufio.NewScanner(bytes.NewReader(bs))
-				for scanner.Scan() {
-					line := scanner.Text()
-					if strings.HasPrefix(line, hostname+",") || strings.HasPrefix(line, "|1|") {
-						continue
-					}
-					newLines = append(newLines, line)
-				}
-				// 写入新内容（先删除旧条目）
-				if err := os.WriteFile(path, []byte(strings.Join(newLines, "\n")+"\n"), f.Mode()); err != nil {
-					return fmt.Errorf("failed to update the known_hosts file: %w", err)
-				}
-			}
-			file, err := os.OpenFile(path,
-				os.O_APPEND|os.O_WRONLY|os.O_CREATE, f.Mode())
-			if err != nil {
-				return fmt.Errorf("unable to open the known_hosts file: %w", err)
-			}
-			defer file.Close()
-
-			_, err = file.WriteString(knownhosts.Line([]string{hostname}, key) + "\n")
-			return err
+		path, err := KnownHostsFile(knownHostsFile)
+		if err != nil {
+			return failedCallback(err)
+		}
+		// 非终端环境无法确认，退化为严格模式
+		if !stdinIsTerminal() {
+			nonTTYWarning.Do(func() {
+				slog.Warn("Standard input is not a terminal, interactive host key verification falls back to strict mode")
+			})
+			return hostCertCallback(path, strictCallback(path))
+		}
+		if promptTimeout <= 0 {
+			promptTimeout = DefaultPromptTimeout
 		}
+		return hostCertCallback(path, interactiveCallback(path, promptTimeout, guard))
 	case SecurityModeStrict:
-		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			_, path, err := getPublicKeyFingerprintFile()
-			if err != nil {
-				return err
-			}
-			callback, err := knownhosts.New(os.ExpandEnv(path))
-			if err != nil {
-				return fmt.Errorf("failed to load the known_hosts file: %w", err)
-			}
-			return callback(hostname, remote, key)
+		path, err := KnownHostsFile(knownHostsFile)
+		if err != nil {
+			return failedCallback(err)
 		}
-
+		return hostCertCallback(path, strictCallback(path))
+	case SecurityModeAcceptNew:
+		path, err := KnownHostsFile(knownHostsFile)
+		if err != nil {
+			return failedCallback(err)
+		}
+		return hostCertCallback(path, acceptNewCallback(path))
 	default:
 		return ssh.InsecureIgnoreHostKey()
 	}
 }
 
-// 获取公钥指纹存储目录
-func getPublicKeyFingerprintFile() (os.FileInfo, string, error) {
-	// 获取用户目录
-	userHomeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to retrieve user home directory: %w", err)
-	}
-	// 判断.ssh是否存在
-	fs, err := os.Stat(path.Join(userHomeDir, ".ssh"))
-	if err != nil {
-		if os.IsNotExist(err) {
-			if err := os.MkdirAll(path.Join(userHomeDir, ".ssh"), 0700); err != nil {
-				return nil, "", fmt.Errorf("failed to create .ssh directory: %w", err)
-			}
-		}
-		return nil, "", fmt.Errorf("unable to access .ssh directory: %w", err)
-	}
-	publicKeyFingerprintPath := path.Join(userHomeDir, ".ssh/known_hosts")
-	// 判断公钥指纹文件是否存在
-	_, err = os.Stat(publicKeyFingerprintPath)
-	if err != nil {
-		// 判断是否因为不存在导致的错误
-		if os.IsNotExist(err) {
-			f, err := os.Create(publicKeyFingerprintPath)
-			if err != nil {
-				return nil, "", fmt.Errorf("failed to create the known_hosts file: %w", err)
-			}
-			f.Close()
-		}
-		return nil, "", fmt.Errorf("unable to access the known_hosts file: %w", err)
+var nonTTYWarning sync.Once
+
+// failedCallback 无法加载known_hosts时拒绝所有密钥
+func failedCallback(err error) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return err
 	}
-	return fs, publicKeyFingerprintPath, nil
 }
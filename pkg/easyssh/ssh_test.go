@@ -0,0 +1,68 @@
+package easyssh
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandshakeTimeout(t *testing.T) {
+	// 接受连接但从不发送ssh版本信息的服务端
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	defer func() {
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+
+	start := time.Now()
+	_, err = NewClient(Opts{
+		IP:             host,
+		Port:           port,
+		User:           "deploy",
+		Passwd:         "secret",
+		ConnectTimeout: 1,
+		Mode:           SecurityModePermissive,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a handshake timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("handshake timeout took %s", elapsed)
+	}
+}
+
+func TestHandshakeGuardPause(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	guard := &handshakeGuard{timeout: 50 * time.Millisecond}
+	guard.start(client)
+
+	// 等待用户确认期间不计时
+	guard.pause()
+	time.Sleep(150 * time.Millisecond)
+	guard.resume()
+	if guard.stop() {
+		t.Fatal("guard expired while paused")
+	}
+
+	guard.start(client)
+	time.Sleep(150 * time.Millisecond)
+	if !guard.stop() {
+		t.Fatal("guard did not expire after the timeout")
+	}
+}
@@ -0,0 +1,116 @@
+// Package report 定义 goss --save json 输出的执行结果格式，其他工具可以直接解析到这些类型：
+//
+//	f, _ := os.Open("2025-01-02T150405.json")
+//	r, err := report.Decode(f)
+//
+// 新增字段不会改变 SchemaVersion，删除或修改已有字段的含义时才会递增
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion 当前结果格式的版本
+const SchemaVersion = 1
+
+// 任务状态
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Report 一次 exec 或 apply 的全部结果
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Summary       Summary   `json:"summary"`
+	Hosts         []Host    `json:"hosts"`
+}
+
+// Summary 结果统计
+type Summary struct {
+	Hosts  int `json:"hosts"`
+	Tasks  int `json:"tasks"`
+	OK     int `json:"ok"`
+	Failed int `json:"failed"`
+}
+
+// Host 单个主机的连接信息与任务结果，按执行顺序排列
+type Host struct {
+	Index int    `json:"index"`
+	Host  string `json:"host"`
+	// 连接失败时为空
+	Connection *Connection `json:"connection,omitempty"`
+	Tasks      []Task      `json:"tasks"`
+}
+
+// Connection 认证方式与协商得到的算法
+type Connection struct {
+	AuthMethod  string `json:"auth_method"`
+	KeyExchange string `json:"key_exchange"`
+	HostKey     string `json:"host_key"`
+	Cipher      string `json:"cipher"`
+	// AEAD加密算法不单独协商MAC，此时为空
+	MAC string `json:"mac,omitempty"`
+}
+
+// Task 单个任务的执行结果
+type Task struct {
+	// 任务在任务列表中的序号，从1开始
+	ID           int    `json:"id"`
+	Type         string `json:"type"`
+	Description  string `json:"description"`
+	Cmd          string `json:"cmd,omitempty"`
+	Local        string `json:"local,omitempty"`
+	Remote       string `json:"remote,omitempty"`
+	RequireSudo  bool   `json:"require_sudo,omitempty"`
+	BecomeMethod string `json:"become_method,omitempty"`
+	BecomeUser   string `json:"become_user,omitempty"`
+	// ok 或 failed
+	Status string `json:"status"`
+	Stdout string `json:"stdout"`
+	// 提权执行时标准错误合并在stdout中
+	Stderr string `json:"stderr"`
+	// 命令退出码，任务未执行、文件传输任务或没有收到退出状态时为null
+	ExitCode *int `json:"exit_code"`
+	// 命令被信号终止时的信号名，例如 KILL
+	Signal    string    `json:"signal,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// 耗时(纳秒)，可直接解析为 time.Duration
+	Duration time.Duration `json:"duration_ns"`
+	// 失败原因，成功时为null
+	Error *Error `json:"error"`
+}
+
+// Error 任务失败原因，cause按包装顺序记录原始错误
+type Error struct {
+	// 错误分类，例如 connection_refused、auth_failed、exit_status、permission、timeout，
+	// 非goss产生的底层错误为空
+	Type      string `json:"type,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Message   string `json:"message"`
+	// 发生时间，底层错误为空
+	Timestamp *time.Time     `json:"timestamp,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Cause     *Error         `json:"cause,omitempty"`
+}
+
+// Decode 解析结果文件，不支持更高版本的格式
+func Decode(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	if report.SchemaVersion == 0 {
+		return nil, fmt.Errorf("missing schema_version, not a goss result report")
+	}
+	if report.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema_version %d, expected at most %d", report.SchemaVersion, SchemaVersion)
+	}
+	return &report, nil
+}